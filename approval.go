@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 待批准通知过期后的处理方式
+const (
+	approvalExpireSend = "send" // 自动发送
+	approvalExpireDrop = "drop" // 直接丢弃
+)
+
+// pendingApproval APPROVAL_MODE 下等待人工批准的一条变更通知
+type pendingApproval struct {
+	ID           string       `json:"id"`
+	Notification Notification `json:"notification"`
+	CreatedAt    time.Time    `json:"created_at"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+}
+
+// approvalQueue 等待批准的通知，由 /approve、/reject 接口和每轮检查读写，有独立的锁
+type approvalQueue struct {
+	mutex sync.Mutex
+	items map[string]pendingApproval
+}
+
+func newApprovalQueue() *approvalQueue {
+	return &approvalQueue{items: make(map[string]pendingApproval)}
+}
+
+// 加入一条待批准通知，返回当前等待批准的数量
+func (q *approvalQueue) Add(pending pendingApproval) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.items[pending.ID] = pending
+	return len(q.items)
+}
+
+// 取出指定的待批准通知，取出后不会再被批准、拒绝或过期处理
+func (q *approvalQueue) Take(id string) (pendingApproval, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	pending, ok := q.items[id]
+	delete(q.items, id)
+	return pending, ok
+}
+
+// 取出所有在 now 之前过期的通知，按创建时间排序
+func (q *approvalQueue) TakeExpired(now time.Time) []pendingApproval {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var expired []pendingApproval
+	for id, pending := range q.items {
+		if !now.Before(pending.ExpiresAt) {
+			expired = append(expired, pending)
+			delete(q.items, id)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].CreatedAt.Before(expired[j].CreatedAt)
+	})
+	return expired
+}
+
+// 返回全部待批准通知的副本，用于写入状态文件
+func (q *approvalQueue) Snapshot() []pendingApproval {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var items []pendingApproval
+	for _, pending := range q.items {
+		items = append(items, pending)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	return items
+}
+
+// 从状态文件恢复待批准通知
+func (q *approvalQueue) Restore(items []pendingApproval) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, pending := range items {
+		q.items[pending.ID] = pending
+	}
+}
+
+// 生成随机的批准 ID
+func newApprovalID() string {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// APPROVAL_MODE 下暂存变更通知，并通过 APPROVAL_NOTIFIER 向审批人发送待批准提醒
+func (s *Service) holdForApproval(ctx context.Context, n Notification) {
+	now := s.Now()
+	pending := pendingApproval{
+		ID:           newApprovalID(),
+		Notification: n,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(time.Duration(s.config().ApprovalTimeoutMinutes) * time.Minute),
+	}
+	count := s.approvals.Add(pending)
+	logEvent("info", "approval", logFields{"approval_id": pending.ID, "title": n.Title, "pending": count},
+		"变更通知等待批准 - ID: %s, 标题: %s, 截止时间: %s", pending.ID, n.Title, s.formatTime(pending.ExpiresAt))
+
+	rt := s.snapshot()
+	var approver Notifier
+	for _, notifier := range rt.notifiers {
+		if notifier.Name() == rt.config.ApprovalNotifier {
+			approver = notifier
+		}
+	}
+	if approver == nil {
+		logErrorf("未找到 APPROVAL_NOTIFIER 对应的通知渠道: %s", rt.config.ApprovalNotifier)
+		return
+	}
+	notice := Notification{
+		Kind:    notifyKindApproval,
+		Title:   fmt.Sprintf("有 %d 条待批准通知", count),
+		Content: s.approvalNotice(pending, count),
+	}
+	if err := s.dispatch(ctx, func() error { return approver.Send(ctx, notice) }); err != nil {
+		logEvent("error", "approval", logFields{"approval_id": pending.ID, "error": err.Error()},
+			"发送待批准提醒失败: %v", err)
+	}
+}
+
+// 待批准提醒的正文：批准方式、过期处理和通知内容预览
+func (s *Service) approvalNotice(pending pendingApproval, count int) string {
+	action := "自动发送"
+	if s.config().ApprovalExpireAction == approvalExpireDrop {
+		action = "自动丢弃"
+	}
+	var notice strings.Builder
+	notice.WriteString(fmt.Sprintf("# 有 %d 条待批准通知\n\n", count))
+	notice.WriteString(fmt.Sprintf("- 批准 ID: %s\n", pending.ID))
+	notice.WriteString(fmt.Sprintf("- 标题: %s\n", pending.Notification.Title))
+	notice.WriteString(fmt.Sprintf("- 截止时间: %s（过期后%s）\n", s.formatTime(pending.ExpiresAt), action))
+	notice.WriteString(fmt.Sprintf("- 批准: POST /approve?id=%s\n", pending.ID))
+	notice.WriteString(fmt.Sprintf("- 拒绝: POST /reject?id=%s\n\n", pending.ID))
+	notice.WriteString("---\n\n")
+	notice.WriteString(pending.Notification.Content)
+	return notice.String()
+}
+
+// 发送已批准的通知，成功后记录通知时间
+func (s *Service) releaseApproval(ctx context.Context, pending pendingApproval) error {
+	if err := s.notify(ctx, pending.Notification); err != nil {
+		logEvent("error", "notify", logFields{"kind": pending.Notification.Kind, "approval_id": pending.ID, "error": err.Error()},
+			"发送已批准的通知失败: %v", err)
+		return err
+	}
+	logEvent("info", "notify", logFields{"kind": pending.Notification.Kind, "approval_id": pending.ID,
+		"change_count": len(pending.Notification.Events)}, "已批准的通知发送成功")
+	s.recordNotified(pending.Notification)
+	return nil
+}
+
+// 处理过期的待批准通知，按 APPROVAL_EXPIRE_ACTION 自动发送或丢弃，每轮检查开始时调用
+func (s *Service) expireApprovals(ctx context.Context) {
+	for _, pending := range s.approvals.TakeExpired(s.Now()) {
+		if s.config().ApprovalExpireAction == approvalExpireDrop {
+			logEvent("warn", "approval", logFields{"approval_id": pending.ID, "title": pending.Notification.Title},
+				"待批准通知在 %d 分钟内未被处理，已丢弃 - ID: %s", s.config().ApprovalTimeoutMinutes, pending.ID)
+			continue
+		}
+		logEvent("warn", "approval", logFields{"approval_id": pending.ID, "title": pending.Notification.Title},
+			"待批准通知在 %d 分钟内未被处理，自动发送 - ID: %s", s.config().ApprovalTimeoutMinutes, pending.ID)
+		pending.Notification.Content = fmt.Sprintf("> ⏱ 该通知在 %d 分钟内未被批准，已自动发送\n\n", s.config().ApprovalTimeoutMinutes) +
+			pending.Notification.Content
+		s.releaseApproval(ctx, pending)
+	}
+}
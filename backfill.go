@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// 回填时每个状态页最多请求的历史分页数，防止接口忽略分页参数时无限循环
+const backfillMaxPages = 100
+
+// 请求一个事件列表接口，返回解析后的事件
+func (s *Service) fetchIncidentList(ctx context.Context, page, path string) ([]Incident, error) {
+	resp, err := s.getStatusPage(ctx, page, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP 状态码异常: %d", resp.StatusCode)
+	}
+
+	body, err := readLimited(resp.Body, s.config().MaxResponseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应内容失败: %v", err)
+	}
+	return decodeIncidents(page, body)
+}
+
+// 回填单个状态页的历史事件：先获取未解决事件，再按 ?page=N 逐页获取历史事件，
+// 某一页为空、请求失败或全部是已见过的事件时停止。返回按 ID 去重后的事件
+func (s *Service) backfillPage(ctx context.Context, page string, seen map[string]bool) []Incident {
+	var collected []Incident
+	collect := func(incidents []Incident) int {
+		added := 0
+		for _, incident := range incidents {
+			if seen[incident.ID] {
+				continue
+			}
+			seen[incident.ID] = true
+			incident.Page = page
+			collected = append(collected, incident)
+			added++
+		}
+		return added
+	}
+
+	unresolved, err := s.fetchIncidentList(ctx, page, "/api/v2/incidents/unresolved.json")
+	if err != nil {
+		logErrorf("状态页 %s 获取未解决事件失败: %v", page, err)
+	} else {
+		logInfof("状态页 %s 未解决事件: %d 个", page, collect(unresolved))
+	}
+
+	for n := 1; n <= backfillMaxPages; n++ {
+		incidents, err := s.fetchIncidentList(ctx, page, "/api/v2/incidents.json?page="+strconv.Itoa(n))
+		if err != nil {
+			logWarnf("状态页 %s 第 %d 页获取失败，停止回填: %v", page, n, err)
+			break
+		}
+		added := collect(incidents)
+		logInfof("状态页 %s 第 %d 页: %d 个事件，新增 %d 个", page, n, len(incidents), added)
+		// 接口不支持分页时每页内容相同，没有新增事件即可认为已到末尾
+		if len(incidents) == 0 || added == 0 {
+			break
+		}
+	}
+	return collected
+}
+
+// -backfill 模式：遍历所有状态页的历史事件写入 SQLite 历史存储，不发送任何通知。
+// 写入按 ID 覆盖，重复运行不会产生重复记录
+func (s *Service) runBackfill(ctx context.Context) error {
+	if s.history == nil {
+		return fmt.Errorf("-backfill 需要配置 DB_PATH")
+	}
+
+	seen := make(map[string]bool)
+	total := 0
+	for _, page := range s.config().StatusPages {
+		incidents := s.backfillPage(ctx, page, seen)
+		if len(incidents) == 0 {
+			continue
+		}
+		if err := s.history.SaveIncidents(incidents); err != nil {
+			return fmt.Errorf("写入状态页 %s 的历史事件失败: %v", page, err)
+		}
+		total += len(incidents)
+	}
+	logInfof("回填完成，共写入 %d 个事件", total)
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// boardNotifier 支持原地更新状态面板消息的通知渠道。messageID 为空时发送一条新消息，
+// 否则更新该消息，返回接收方的消息 ID；不支持编辑的渠道按 STATUS_BOARD_REPOST_MINUTES 定期重新发送
+type boardNotifier interface {
+	Notifier
+	SendBoard(ctx context.Context, n Notification, messageID string) (string, error)
+}
+
+// 生成状态面板的事件列表，按影响程度从高到低、创建时间从早到晚排列，调用方需持有锁。
+// 不包含时间等每轮都会变化的内容，用于判断面板是否需要更新
+func (s *Service) renderStatusBoardBody() string {
+	var active []Incident
+	for _, incident := range s.lastIncidents {
+		if !incident.isResolved() {
+			active = append(active, incident)
+		}
+	}
+	if len(active) == 0 {
+		return "✅ 当前没有进行中的事件\n"
+	}
+	sort.Slice(active, func(i, j int) bool {
+		if rankA, rankB := impactRank[active[i].Impact], impactRank[active[j].Impact]; rankA != rankB {
+			return rankA > rankB
+		}
+		if !active[i].CreatedAt.Equal(active[j].CreatedAt) {
+			return active[i].CreatedAt.Before(active[j].CreatedAt)
+		}
+		// 事件来自 map，开始时间相同时按 ID 排列，保证内容未变化时生成的面板相同
+		return active[i].ID < active[j].ID
+	})
+
+	var body strings.Builder
+	body.WriteString(s.activeSeveritySummary(active))
+	for _, incident := range active {
+		body.WriteString(fmt.Sprintf("- %s%s，开始于 %s\n", s.pagePrefix(incident), s.formatIncidentCompact(incident),
+			s.formatTime(incident.CreatedAt)))
+	}
+	return body.String()
+}
+
+// 开启 STATUS_BOARD 时每轮检查后更新状态面板：支持编辑的渠道在进行中的事件有变化时原地更新同一条消息，
+// 其他渠道（如钉钉）在内容变化且距上次发送超过 STATUS_BOARD_REPOST_MINUTES 时重新发送一条
+func (s *Service) updateStatusBoard(ctx context.Context) {
+	if s.config().ObserveOnly {
+		return
+	}
+	now := s.Now()
+	repostInterval := time.Duration(s.config().StatusBoardRepostMinutes) * time.Minute
+
+	s.mutex.RLock()
+	body := s.renderStatusBoardBody()
+	header := notificationHeader(s.statusVersion, s.formatTime(now))
+	var edits []boardNotifier
+	var reposts []Notifier
+	for _, notifier := range s.snapshot().notifiers {
+		name := notifier.Name()
+		if editor, ok := notifier.(boardNotifier); ok {
+			if s.boardBodies[name] != body {
+				edits = append(edits, editor)
+			}
+			continue
+		}
+		if s.boardBodies[name] != body && now.Sub(s.boardPostedAt[name]) >= repostInterval {
+			reposts = append(reposts, notifier)
+		}
+	}
+	messageIDs := make(map[string]string, len(edits))
+	for _, editor := range edits {
+		messageIDs[editor.Name()] = s.boardMessageIDs[editor.Name()]
+	}
+	s.mutex.RUnlock()
+	if len(edits) == 0 && len(reposts) == 0 {
+		return
+	}
+
+	n := Notification{
+		Kind:    notifyKindStatusBoard,
+		Title:   "Cloudflare 进行中事件",
+		Content: "# Cloudflare 进行中事件\n\n" + header + body + "\n---\n" + s.notificationFooter(nil),
+	}
+	err := s.dispatch(ctx, func() error {
+		var failed []string
+		for _, editor := range edits {
+			name := editor.Name()
+			messageID, err := editor.SendBoard(ctx, n, messageIDs[name])
+			if err != nil {
+				logEvent("error", "notify", logFields{"notifier": name, "kind": n.Kind, "error": err.Error()},
+					"通过 %s 更新状态面板失败: %v", name, err)
+				failed = append(failed, name)
+				continue
+			}
+			s.recordBoardSent(name, body, messageID)
+			logEvent("info", "notify", logFields{"notifier": name, "kind": n.Kind, "edited": messageIDs[name] != ""},
+				"通过 %s 更新状态面板成功", name)
+		}
+		for _, notifier := range reposts {
+			name := notifier.Name()
+			if err := notifier.Send(ctx, n); err != nil {
+				logEvent("error", "notify", logFields{"notifier": name, "kind": n.Kind, "error": err.Error()},
+					"通过 %s 发送状态面板失败: %v", name, err)
+				failed = append(failed, name)
+				continue
+			}
+			s.recordBoardSent(name, body, "")
+			logEvent("info", "notify", logFields{"notifier": name, "kind": n.Kind},
+				"通过 %s 发送状态面板成功", name)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("以下通知渠道发送失败: %s", strings.Join(failed, ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		logErrorf("更新状态面板失败: %v", err)
+	}
+}
+
+// 记录状态面板在某个渠道中发送成功的内容和时间，messageID 非空时记录为该渠道后续编辑的消息
+func (s *Service) recordBoardSent(notifier, body, messageID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.boardBodies == nil {
+		s.boardBodies = make(map[string]string)
+	}
+	if s.boardPostedAt == nil {
+		s.boardPostedAt = make(map[string]time.Time)
+	}
+	if s.boardMessageIDs == nil {
+		s.boardMessageIDs = make(map[string]string)
+	}
+	s.boardBodies[notifier] = body
+	s.boardPostedAt[notifier] = s.Now()
+	if messageID != "" {
+		s.boardMessageIDs[notifier] = messageID
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// recordingBoardNotifier 支持原地编辑状态面板的渠道，记录每次更新时传入的消息 ID
+type recordingBoardNotifier struct {
+	recordingNotifier
+	messageIDs []string
+}
+
+func (b *recordingBoardNotifier) Name() string {
+	return "board"
+}
+
+func (b *recordingBoardNotifier) SendBoard(ctx context.Context, n Notification, messageID string) (string, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.sent = append(b.sent, n)
+	b.messageIDs = append(b.messageIDs, messageID)
+	return "board-1", nil
+}
+
+// 不支持编辑的渠道（钉钉）在内容变化且距上次发送超过 STATUS_BOARD_REPOST_MINUTES 时才重新发送；
+// 支持编辑的渠道在内容变化时编辑同一条消息；内容未变化时都不发送
+func TestUpdateStatusBoard(t *testing.T) {
+	dingtalk := newFakeDingtalk(t)
+	s := newTestService(t, "STATUS_BOARD=true\nSTATUS_BOARD_REPOST_MINUTES=30\nDINGTALK_BASE_URL="+dingtalk.URL+"\n")
+	now := testNow
+	s.Now = func() time.Time { return now }
+	buildTestNotifiers(t, s)
+	editor := &recordingBoardNotifier{}
+	setTestNotifiers(s, append(s.snapshot().notifiers, editor)...)
+	ctx := context.Background()
+	s.lastIncidents = map[string]Incident{"inc1": testIncident("inc1", "investigating", "major", time.Hour)}
+
+	steps := []struct {
+		name         string
+		after        time.Duration // 距第一次更新的时间
+		add          string        // 本次更新前新增的事件
+		wantReposts  int
+		wantEdits    int
+		wantEditedID string // 最近一次编辑传入的消息 ID
+	}{
+		{name: "首次发送", wantReposts: 1, wantEdits: 1, wantEditedID: ""},
+		{name: "内容未变化", after: 10 * time.Minute, wantReposts: 1, wantEdits: 1, wantEditedID: ""},
+		{name: "内容变化但未到重新发送间隔", after: 20 * time.Minute, add: "inc2", wantReposts: 1, wantEdits: 2, wantEditedID: "board-1"},
+		{name: "仍未到重新发送间隔", after: 25 * time.Minute, wantReposts: 1, wantEdits: 2, wantEditedID: "board-1"},
+		{name: "到达重新发送间隔", after: 30 * time.Minute, wantReposts: 2, wantEdits: 2, wantEditedID: "board-1"},
+		{name: "重新发送后内容再次变化", after: 45 * time.Minute, add: "inc3", wantReposts: 2, wantEdits: 3, wantEditedID: "board-1"},
+		{name: "再次到达重新发送间隔", after: 90 * time.Minute, wantReposts: 3, wantEdits: 3, wantEditedID: "board-1"},
+		{name: "超过间隔但内容未变化", after: 130 * time.Minute, wantReposts: 3, wantEdits: 3, wantEditedID: "board-1"},
+	}
+	for _, step := range steps {
+		now = testNow.Add(step.after)
+		if step.add != "" {
+			s.mutex.Lock()
+			// 新增的事件开始时间相同，面板按 ID 排列
+			s.lastIncidents[step.add] = testIncident(step.add, "investigating", "minor", time.Minute)
+			s.mutex.Unlock()
+		}
+		s.updateStatusBoard(ctx)
+		reposts := dingtalk.received()
+		if len(reposts) != step.wantReposts {
+			t.Errorf("%s: 钉钉重新发送 %d 次, 期望 %d 次", step.name, len(reposts), step.wantReposts)
+		} else if title := reposts[len(reposts)-1].Markdown.Title; title != "Cloudflare 进行中事件" {
+			t.Errorf("%s: 钉钉面板消息标题 = %q", step.name, title)
+		}
+		editor.mutex.Lock()
+		edits, lastID := len(editor.messageIDs), ""
+		if edits > 0 {
+			lastID = editor.messageIDs[edits-1]
+		}
+		editor.mutex.Unlock()
+		if edits != step.wantEdits || lastID != step.wantEditedID {
+			t.Errorf("%s: 编辑 %d 次、消息 ID %q, 期望 %d 次、%q", step.name, edits, lastID, step.wantEdits, step.wantEditedID)
+		}
+	}
+}
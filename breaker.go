@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	breakerClosed   = "closed"    // 正常发送
+	breakerOpen     = "open"      // 冷却中，跳过发送
+	breakerHalfOpen = "half_open" // 冷却结束，放行一次发送探测是否恢复
+)
+
+// circuitBreaker 连续发送失败达到阈值后在冷却期内跳过发送，避免持续请求已故障的接口
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool // 半开状态下是否已有探测请求在进行
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, state: breakerClosed}
+}
+
+// 判断是否允许发送。冷却期内返回错误；冷却结束后进入半开状态，只放行一次探测
+func (b *circuitBreaker) Allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		remaining := b.cooldown - time.Since(b.openedAt)
+		if remaining > 0 {
+			return fmt.Errorf("钉钉熔断器已打开，%v 后尝试恢复，跳过发送", remaining.Round(time.Second))
+		}
+		logEvent("info", "dingtalk", logFields{"breaker": breakerHalfOpen}, "钉钉熔断器冷却结束，进入半开状态，发送探测消息")
+		b.state = breakerHalfOpen
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("钉钉熔断器半开，正在等待探测结果，跳过发送")
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// 记录一次发送结果。半开状态下探测失败立即重新打开，成功则关闭熔断器
+func (b *circuitBreaker) Record(failed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.probing = false
+	if !failed {
+		if b.state != breakerClosed {
+			logEvent("info", "dingtalk", logFields{"breaker": breakerClosed}, "钉钉发送已恢复，熔断器关闭")
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		if b.state != breakerOpen {
+			logEvent("warn", "dingtalk", logFields{"breaker": breakerOpen, "consecutive_failures": b.failures},
+				"钉钉连续发送失败 %d 次，熔断器打开，%v 内跳过发送", b.failures, b.cooldown)
+		}
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// 熔断器当前状态，用于健康检查接口
+func (b *circuitBreaker) Snapshot() map[string]interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	snapshot := map[string]interface{}{
+		"state":                b.state,
+		"consecutive_failures": b.failures,
+	}
+	if b.state == breakerOpen {
+		snapshot["open_until"] = b.openedAt.Add(b.cooldown).Format(time.RFC3339)
+	}
+	return snapshot
+}
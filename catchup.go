@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 启动时补发监控离线期间的事件：以历史存储中最近一次检查的时间为离线起点，
+// 汇总此后有活动、且历史存储中没有或版本较旧的事件，合并为一条通知发送。
+// 离线时长未超过两个检查间隔时视为正常重启，不做处理
+func (s *Service) catchUp(ctx context.Context) {
+	lastSeen, err := s.history.LastSeen()
+	if err != nil {
+		logWarnf("读取最近检查时间失败，跳过离线补发: %v", err)
+		return
+	}
+	if lastSeen.IsZero() {
+		logInfof("历史存储中没有最近检查时间，跳过离线补发")
+		return
+	}
+	now := s.Now()
+	offline := now.Sub(lastSeen)
+	if offline < 2*time.Duration(s.config().CheckIntervalMinutes)*time.Minute {
+		return
+	}
+	logInfof("监控已离线 %v（自 %s 起），开始检查离线期间的事件",
+		offline.Round(time.Minute), lastSeen.Format("2006-01-02 15:04:05"))
+
+	var missed []Incident
+	for _, page := range s.config().StatusPages {
+		incidents, err := s.fetchIncidentList(ctx, page, "/api/v2/incidents.json")
+		if err != nil {
+			logWarnf("状态页 %s 获取事件失败，跳过离线补发: %v", page, err)
+			continue
+		}
+		for _, incident := range incidents {
+			incident.Page = page
+			if !incident.UpdatedAt.After(lastSeen) {
+				continue
+			}
+			storedAt, exists, err := s.history.IncidentUpdatedAt(incident.ID)
+			if err != nil {
+				logErrorf("查询事件历史失败 - ID: %s, 错误: %v", incident.ID, err)
+				continue
+			}
+			if exists && !incident.UpdatedAt.After(storedAt) {
+				continue
+			}
+			missed = append(missed, incident)
+		}
+	}
+	if len(missed) == 0 {
+		logInfof("离线期间没有新的事件活动")
+		return
+	}
+
+	// 已补发的事件写入缓存和历史存储，避免随后的首次检查重复通知
+	s.mutex.Lock()
+	if s.lastIncidents != nil {
+		for _, incident := range missed {
+			s.lastIncidents[incident.ID] = incident
+		}
+	}
+	header := notificationHeader(s.statusVersion, s.formatTime(s.Now()))
+	s.mutex.Unlock()
+	if err := s.history.SaveIncidents(missed); err != nil {
+		logErrorf("写入事件历史失败: %v", err)
+	}
+
+	var lines []string
+	for _, incident := range missed {
+		lines = append(lines, "- "+s.formatIncidentCompact(incident)+"\n")
+	}
+	content := "# Cloudflare 离线期间事件汇总\n\n" + header +
+		fmt.Sprintf("监控离线时段: %s 至 %s（约 %v），期间有 %d 个事件发生变化:\n\n",
+			s.formatTime(lastSeen), s.formatTime(now), offline.Round(time.Minute), len(missed)) +
+		strings.Join(lines, "") + "\n---\n" +
+		s.notificationFooter(nil)
+	if err := s.notify(ctx, Notification{
+		Kind:    notifyKindCatchUp,
+		Title:   "Cloudflare 离线期间事件汇总",
+		Content: content,
+	}); err != nil {
+		logErrorf("发送离线期间事件汇总失败: %v", err)
+	}
+}
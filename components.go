@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Component 结构体用于解析组件状态数据
+type Component struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Group     bool      `json:"group"`
+	GroupID   string    `json:"group_id,omitempty"`
+	Showcase  bool      `json:"showcase"` // 是否在状态页上展示可用率
+	UpdatedAt time.Time `json:"updated_at"`
+	Page      string    `json:"page,omitempty"` // 组件所属的状态页地址
+}
+
+// SummaryResponse 结构体用于解析 summary.json 的组件部分
+type SummaryResponse struct {
+	Components []Component `json:"components"`
+}
+
+// 组件状态的中文描述
+var componentStatusNames = map[string]string{
+	"operational":          "正常",
+	"degraded_performance": "性能下降",
+	"partial_outage":       "部分中断",
+	"major_outage":         "严重中断",
+	"under_maintenance":    "维护中",
+}
+
+func componentStatusName(status string) string {
+	if name, ok := componentStatusNames[status]; ok {
+		return name
+	}
+	return status
+}
+
+// 获取单个状态页的组件列表，忽略分组组件
+func (s *Service) fetchPageComponents(ctx context.Context, page string) ([]Component, error) {
+	resp, err := s.getStatusPage(ctx, page, "/api/v2/summary.json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取组件状态失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := readLimited(resp.Body, s.config().MaxResponseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("读取组件状态失败: %v", err)
+	}
+
+	var summary SummaryResponse
+	if err := json.Unmarshal(body, &summary); err != nil {
+		return nil, fmt.Errorf("解析组件状态失败: %v", err)
+	}
+	s.rememberComponentGroups(page, summary.Components)
+	return leafComponents(page, summary.Components), nil
+}
+
+// 记录分组组件的名称，用于按分组名称订阅组件
+func (s *Service) rememberComponentGroups(page string, all []Component) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.componentGroups == nil {
+		s.componentGroups = make(map[string]string)
+	}
+	for _, component := range all {
+		if component.Group {
+			s.componentGroups[page+"|"+component.ID] = component.Name
+		}
+	}
+}
+
+// 组件是否在 WATCH_COMPONENTS 中：组件名称或所属分组名称与订阅的名称相同（不区分大小写），调用方需持有锁
+func (s *Service) componentWatched(component Component) bool {
+	names := []string{strings.ToLower(component.Name)}
+	if group, ok := s.componentGroups[component.Page+"|"+component.GroupID]; ok && component.GroupID != "" {
+		names = append(names, strings.ToLower(group))
+	}
+	for _, watched := range s.config().WatchComponents {
+		for _, name := range names {
+			if name == watched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 事件是否影响订阅的组件，调用方需持有锁。事件中的组件没有标记状态页时按事件所属状态页查找分组
+func (s *Service) incidentWatched(incident Incident) bool {
+	for _, component := range incident.Components {
+		if component.Page == "" {
+			component.Page = incident.Page
+		}
+		if s.componentWatched(component) {
+			return true
+		}
+	}
+	return false
+}
+
+// 启动时输出各状态页可订阅的组件名称，并提示 WATCH_COMPONENTS 中没有匹配任何组件的名称
+func (s *Service) logWatchableComponents(ctx context.Context) {
+	matched := make(map[string]bool)
+	for _, page := range s.config().StatusPages {
+		components, err := s.fetchPageComponents(ctx, page)
+		if err != nil {
+			logWarnf("状态页 %s 组件列表获取失败，无法列出可订阅的组件: %v", page, err)
+			continue
+		}
+		s.mutex.RLock()
+		groups := make(map[string]bool)
+		var names []string
+		for _, component := range components {
+			names = append(names, component.Name)
+			group := s.componentGroups[page+"|"+component.GroupID]
+			if group != "" && !groups[group] {
+				groups[group] = true
+				names = append(names, group+"（分组）")
+			}
+			for _, watched := range s.config().WatchComponents {
+				if strings.ToLower(component.Name) == watched || strings.ToLower(group) == watched {
+					matched[watched] = true
+				}
+			}
+		}
+		s.mutex.RUnlock()
+		sort.Strings(names)
+		logInfof("状态页 %s 可订阅的组件（共 %d 个）: %s", page, len(components), strings.Join(names, "; "))
+	}
+	for _, watched := range s.config().WatchComponents {
+		if !matched[watched] {
+			logWarnf("WATCH_COMPONENTS 中的 %q 没有匹配任何组件，请检查名称是否与上面列出的一致", watched)
+		}
+	}
+}
+
+// 过滤掉分组组件，并标记组件所属的状态页
+func leafComponents(page string, all []Component) []Component {
+	var components []Component
+	for _, component := range all {
+		if component.Group {
+			continue
+		}
+		component.Page = page
+		components = append(components, component)
+	}
+	return components
+}
+
+// componentCacheEntry components.json 上次成功响应的缓存验证信息和组件，用于条件请求
+type componentCacheEntry struct {
+	etag         string
+	lastModified string
+	components   []Component
+}
+
+// 通过 components.json 获取单个状态页的组件列表，携带上次响应的 ETag/Last-Modified，
+// 返回 304 时直接使用缓存的组件
+func (s *Service) fetchComponentList(ctx context.Context, page string) ([]Component, error) {
+	s.mutex.RLock()
+	cached, hasCache := s.componentCache[page]
+	s.mutex.RUnlock()
+	conditional := make(http.Header)
+	if hasCache {
+		if cached.etag != "" {
+			conditional.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			conditional.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := s.getStatusPage(ctx, page, "/api/v2/components.json", conditional)
+	if err != nil {
+		return nil, fmt.Errorf("获取组件列表失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && hasCache {
+		return cached.components, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取组件列表失败: HTTP 状态码 %d", resp.StatusCode)
+	}
+
+	body, err := readLimited(resp.Body, s.config().MaxResponseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("读取组件列表失败: %v", err)
+	}
+	var summary SummaryResponse
+	if err := json.Unmarshal(body, &summary); err != nil {
+		return nil, fmt.Errorf("解析组件列表失败: %v", err)
+	}
+	components := leafComponents(page, summary.Components)
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	s.mutex.Lock()
+	if etag != "" || lastModified != "" {
+		if s.componentCache == nil {
+			s.componentCache = make(map[string]componentCacheEntry)
+		}
+		s.componentCache[page] = componentCacheEntry{etag: etag, lastModified: lastModified, components: components}
+	} else {
+		delete(s.componentCache, page)
+	}
+	s.mutex.Unlock()
+	return components, nil
+}
+
+// 更新变更通知中使用的非正常组件数量，所有状态页都获取失败时记为未知
+func (s *Service) refreshComponentSummary(ctx context.Context) {
+	degraded, fetched := 0, false
+	for _, page := range s.config().StatusPages {
+		components, err := s.fetchComponentList(ctx, page)
+		if err != nil {
+			logErrorf("状态页 %s 组件列表获取失败: %v", page, err)
+			continue
+		}
+		fetched = true
+		for _, component := range components {
+			if component.Status != "operational" {
+				degraded++
+			}
+		}
+	}
+	if !fetched {
+		degraded = -1
+	}
+
+	s.mutex.Lock()
+	s.degradedComponents = degraded
+	s.mutex.Unlock()
+}
+
+// 变更通知末尾的组件概况，未启用或数量未知时为空，调用方需持有锁
+func (s *Service) componentSummaryLine() string {
+	if !s.config().IncludeComponentSummary || s.degradedComponents < 0 {
+		return ""
+	}
+	if s.degradedComponents == 0 {
+		return "当前所有组件运行正常\n\n"
+	}
+	return fmt.Sprintf("当前 %d 个组件处于非正常状态\n\n", s.degradedComponents)
+}
+
+// 检查组件状态变化并发送通知，首次获取时只记录不通知
+func (s *Service) checkComponents(ctx context.Context) {
+	var current []Component
+	for _, page := range s.config().StatusPages {
+		components, err := s.fetchPageComponents(ctx, page)
+		if err != nil {
+			logErrorf("状态页 %s 组件状态获取失败: %v", page, err)
+			continue
+		}
+		current = append(current, components...)
+	}
+	logDebugf("获取到 %d 个组件状态", len(current))
+
+	s.mutex.Lock()
+	firstRun := s.componentStatus == nil
+	if firstRun {
+		s.componentStatus = make(map[string]Component)
+	}
+	var changes []string
+	for _, component := range current {
+		key := component.Page + "|" + component.ID
+		old, exists := s.componentStatus[key]
+		s.componentStatus[key] = component
+		if firstRun || !exists || old.Status == component.Status {
+			continue
+		}
+		if len(s.config().WatchComponents) > 0 && !s.componentWatched(component) {
+			logDebugf("组件未订阅，跳过通知 - %s: %s -> %s", component.Name, old.Status, component.Status)
+			continue
+		}
+		logInfof("组件状态变化 - %s: %s -> %s", component.Name, old.Status, component.Status)
+		changes = append(changes, fmt.Sprintf("- **%s**: %s → %s\n",
+			component.Name, componentStatusName(old.Status), componentStatusName(component.Status)))
+	}
+	header := notificationHeader(s.statusVersion, s.formatTime(s.Now()))
+	s.mutex.Unlock()
+
+	if len(changes) == 0 {
+		return
+	}
+
+	content := "# Cloudflare 组件状态变化\n\n" + header +
+		strings.Join(changes, "") + "\n---\n" +
+		s.notificationFooter(nil)
+	if err := s.notify(ctx, Notification{
+		Kind:    notifyKindComponent,
+		Title:   "Cloudflare 组件状态变化",
+		Content: content,
+	}); err != nil {
+		logErrorf("发送组件状态通知失败: %v", err)
+	}
+}
+
+// 生成非正常组件列表，调用方需持有锁
+func (s *Service) formatDegradedComponents() string {
+	var degraded []Component
+	for _, component := range s.componentStatus {
+		if component.Status != "operational" {
+			degraded = append(degraded, component)
+		}
+	}
+	sort.Slice(degraded, func(i, j int) bool {
+		return degraded[i].Name < degraded[j].Name
+	})
+
+	var table strings.Builder
+	table.WriteString("## 非正常组件\n\n")
+	if len(degraded) == 0 {
+		table.WriteString("所有组件运行正常。\n\n")
+		return table.String()
+	}
+	table.WriteString("| 组件 | 状态 |\n")
+	table.WriteString("| --- | --- |\n")
+	for _, component := range degraded {
+		table.WriteString(fmt.Sprintf("| %s | %s |\n", component.Name, componentStatusName(component.Status)))
+	}
+	table.WriteString("\n")
+	return table.String()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// countingReader 统计已读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// gzipBody 解压 gzip 编码的响应内容，同时统计压缩前后的字节数。
+// 解压器在首次读取时才创建，没有内容的响应（如 304）不会因缺少 gzip 头而报错
+type gzipBody struct {
+	raw          io.ReadCloser
+	compressed   *countingReader
+	zr           *gzip.Reader
+	decompressed int64
+}
+
+func (g *gzipBody) Read(p []byte) (int, error) {
+	if g.zr == nil {
+		zr, err := gzip.NewReader(g.compressed)
+		if err != nil {
+			return 0, err
+		}
+		g.zr = zr
+	}
+	n, err := g.zr.Read(p)
+	g.decompressed += int64(n)
+	return n, err
+}
+
+func (g *gzipBody) Close() error {
+	return g.raw.Close()
+}
+
+// 显式请求 gzip 后标准库不再自动解压，服务器返回 gzip 编码时替换为解压后的响应内容，
+// 后续的 readLimited 按解压后的字节数限制大小
+func decompressResponse(resp *http.Response) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return
+	}
+	counter := &countingReader{r: resp.Body}
+	resp.Body = &gzipBody{raw: resp.Body, compressed: counter}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// 响应内容读取完成后的压缩前后字节数，响应未压缩时 ok 为 false
+func compressionStats(resp *http.Response) (compressed, decompressed int64, ok bool) {
+	body, ok := resp.Body.(*gzipBody)
+	if !ok {
+		return 0, 0, false
+	}
+	return body.compressed.n, body.decompressed, true
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+)
+
+// 判断事件的变化是否会触发高优先级告警（@所有人）：新出现的 critical 事件、影响程度升级或重新开启，调用方需持有锁
+func (s *Service) isHighSeverityChange(incident Incident) bool {
+	if incident.isResolved() {
+		return false
+	}
+	cached, ok := s.lastIncidents[incident.ID]
+	if !ok {
+		return impactRank[incident.Impact] >= impactRank["critical"]
+	}
+	return impactRank[incident.Impact] > impactRank[cached.Impact] || (cached.isResolved() && incident.isActiveStatus())
+}
+
+// 开启 CONFIRM_CRITICAL_ALERTS 时，对会触发高优先级告警的事件立即重新获取一次所属状态页确认：
+// 确认结果中事件仍然存在、未解决且影响程度相同时照常处理；结果不一致时本轮按缓存中的状态处理该事件
+// （新事件直接忽略），下一轮检查重新判断。确认请求失败时不阻止告警，避免漏报
+func (s *Service) confirmHighSeverityChanges(ctx context.Context, incidents []Incident) []Incident {
+	s.mutex.RLock()
+	if s.lastIncidents == nil {
+		s.mutex.RUnlock()
+		return incidents
+	}
+	var pages []string
+	seenPages := make(map[string]bool)
+	for _, incident := range incidents {
+		if s.isHighSeverityChange(incident) && !seenPages[incident.Page] {
+			seenPages[incident.Page] = true
+			pages = append(pages, incident.Page)
+		}
+	}
+	s.mutex.RUnlock()
+	if len(pages) == 0 {
+		return incidents
+	}
+
+	confirmed := make(map[string]Incident)
+	fetched := make(map[string]bool)
+	for _, page := range pages {
+		refetched, _, err := s.fetchPageIncidents(ctx, page)
+		if err != nil {
+			logWarnf("高优先级告警确认请求失败，照常发送 - 状态页: %s, 错误: %v", page, err)
+			continue
+		}
+		fetched[page] = true
+		for _, incident := range refetched {
+			confirmed[incident.ID] = incident
+		}
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	result := make([]Incident, 0, len(incidents))
+	for _, incident := range incidents {
+		if !fetched[incident.Page] || !s.isHighSeverityChange(incident) {
+			result = append(result, incident)
+			continue
+		}
+		again, ok := confirmed[incident.ID]
+		if ok && !again.isResolved() && again.Impact == incident.Impact {
+			logInfof("高优先级告警已确认 - ID: %s, 名称: %s, 影响程度: %s", incident.ID, incident.Name, incident.Impact)
+			result = append(result, incident)
+			continue
+		}
+		logEvent("warn", "detector", logFields{"incident_id": incident.ID, "change": "unconfirmed", "impact": incident.Impact},
+			"高优先级告警未通过确认，推迟到下一轮检查 - ID: %s, 名称: %s, 影响程度: %s, 确认结果: %s",
+			incident.ID, incident.Name, incident.Impact, confirmationSummary(again, ok))
+		if cached, exists := s.lastIncidents[incident.ID]; exists {
+			result = append(result, cached)
+		}
+	}
+	return result
+}
+
+// 确认请求中事件的状态，用于日志
+func confirmationSummary(incident Incident, found bool) string {
+	if !found {
+		return "事件不存在"
+	}
+	return incident.Status + "/" + incident.Impact
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 事件关联的判定参数
+const (
+	// 名称关键词的 Jaccard 相似度达到该值时，无论时间是否重叠都视为相关
+	correlationMinSimilarity = 0.5
+	// 两个事件的持续时间相隔不超过该时长时视为时间重叠
+	correlationMaxGap = 30 * time.Minute
+)
+
+// 事件名称中不具有区分度的常见词，计算相似度时忽略
+var correlationStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "of": true, "in": true, "on": true, "for": true,
+	"to": true, "with": true, "some": true, "users": true, "customers": true, "cloudflare": true,
+	"issue": true, "issues": true, "error": true, "errors": true, "elevated": true, "increased": true,
+	"degraded": true, "performance": true, "affecting": true, "impacting": true, "service": true,
+	"services": true, "problems": true, "intermittent": true,
+}
+
+// 将事件名称拆分为小写单词，忽略标点和多余空白
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// 事件名称中有区分度的关键词集合
+func significantTokens(name string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, word := range nameWords(name) {
+		if len(word) > 1 && !correlationStopWords[word] {
+			tokens[word] = true
+		}
+	}
+	return tokens
+}
+
+// 两个事件名称关键词的 Jaccard 相似度和共同关键词
+func nameSimilarity(a, b string) (float64, []string) {
+	ta, tb := significantTokens(a), significantTokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0, nil
+	}
+	var shared []string
+	for token := range ta {
+		if tb[token] {
+			shared = append(shared, token)
+		}
+	}
+	sort.Strings(shared)
+	union := len(ta) + len(tb) - len(shared)
+	return float64(len(shared)) / float64(union), shared
+}
+
+// 两个事件的持续时间是否重叠，未解决的事件持续到 now
+func incidentsOverlap(a, b Incident, now time.Time) bool {
+	end := func(incident Incident) time.Time {
+		if resolvedAt := incident.resolvedTime(); !resolvedAt.IsZero() {
+			return resolvedAt
+		}
+		return now
+	}
+	return !a.CreatedAt.After(end(b).Add(correlationMaxGap)) && !b.CreatedAt.After(end(a).Add(correlationMaxGap))
+}
+
+// 判断两个事件是否相关：名称高度相似，或时间重叠且至少有一个共同关键词
+func incidentsCorrelated(a, b Incident, now time.Time) bool {
+	similarity, shared := nameSimilarity(a.Name, b.Name)
+	if similarity >= correlationMinSimilarity {
+		return true
+	}
+	return len(shared) > 0 && incidentsOverlap(a, b, now)
+}
+
+// 将相关的事件分组，相关关系可传递。分组按首个成员在输入中的位置排序，组内保持输入顺序；
+// sameGroup 不为 nil 时只在其返回 true 的事件之间关联
+func correlateIncidents(incidents []Incident, now time.Time, sameGroup func(a, b Incident) bool) [][]Incident {
+	parent := make([]int, len(incidents))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range incidents {
+		for j := i + 1; j < len(incidents); j++ {
+			if sameGroup != nil && !sameGroup(incidents[i], incidents[j]) {
+				continue
+			}
+			if incidentsCorrelated(incidents[i], incidents[j], now) {
+				if ri, rj := find(i), find(j); ri != rj {
+					if ri < rj {
+						parent[rj] = ri
+					} else {
+						parent[ri] = rj
+					}
+				}
+			}
+		}
+	}
+
+	index := make(map[int]int)
+	var groups [][]Incident
+	for i, incident := range incidents {
+		root := find(i)
+		if n, ok := index[root]; ok {
+			groups[n] = append(groups[n], incident)
+			continue
+		}
+		index[root] = len(groups)
+		groups = append(groups, []Incident{incident})
+	}
+	return groups
+}
+
+// 关联事件组的标题，列出组内事件共同的关键词
+func correlationHeading(group []Incident) string {
+	counts := make(map[string]int)
+	for _, incident := range group {
+		for token := range significantTokens(incident.Name) {
+			counts[token]++
+		}
+	}
+	var shared []string
+	for token, count := range counts {
+		if count > 1 {
+			shared = append(shared, token)
+		}
+	}
+	sort.Strings(shared)
+	heading := fmt.Sprintf("🔗 %d 个相关事件", len(group))
+	if len(shared) > 0 {
+		heading += "（" + strings.Join(shared, ", ") + "）"
+	}
+	return heading
+}
+
+// 按事件关联关系重排变更通知的各个部分，相关的变化放在同一标题下，调用方需持有锁。
+// 单独的变化如果与缓存中其他进行中的事件相关，在其后注明可能相关的事件
+func (s *Service) correlatedSections(changes []incidentChange) []string {
+	incidents := make([]Incident, len(changes))
+	sections := make(map[string]string, len(changes))
+	inBatch := make(map[string]bool, len(changes))
+	for i, change := range changes {
+		incidents[i] = change.Event.Incident
+		sections[change.Event.Incident.ID] += change.Section
+		inBatch[change.Event.Incident.ID] = true
+	}
+
+	now := s.Now()
+	var result []string
+	written := make(map[string]bool)
+	for _, group := range correlateIncidents(incidents, now, nil) {
+		if len(group) > 1 {
+			result = append(result, "## "+correlationHeading(group)+"\n")
+		}
+		for _, incident := range group {
+			if written[incident.ID] {
+				continue
+			}
+			written[incident.ID] = true
+			section := sections[incident.ID]
+			if len(group) == 1 {
+				if related := s.relatedActiveIncidents(incident, inBatch, now); len(related) > 0 {
+					section = strings.TrimRight(section, "\n") + "\n\n> 可能与进行中的事件相关: " + strings.Join(related, "、") + "\n"
+				}
+			}
+			result = append(result, section)
+		}
+	}
+	return result
+}
+
+// 缓存中与给定事件相关且仍未解决的其他事件名称，调用方需持有锁
+func (s *Service) relatedActiveIncidents(incident Incident, exclude map[string]bool, now time.Time) []string {
+	var related []string
+	for id, cached := range s.lastIncidents {
+		if exclude[id] || cached.isResolved() {
+			continue
+		}
+		if incidentsCorrelated(incident, cached, now) {
+			related = append(related, cached.Name)
+		}
+	}
+	sort.Strings(related)
+	return related
+}
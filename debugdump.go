@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 调试转储文件名前缀，清理旧文件时只处理带该前缀的文件
+const debugDumpPrefix = "incidents-"
+
+// 将本轮解析并排序后的事件写入 DEBUG_DUMP_DIR 下带时间戳的 JSON 文件，
+// 只保留最近 DEBUG_DUMP_KEEP 个文件。转储失败只记录日志，不影响检查
+func (s *Service) dumpIncidents(incidents []Incident, now time.Time) {
+	data, err := json.MarshalIndent(incidents, "", "  ")
+	if err != nil {
+		logErrorf("序列化调试转储失败: %v", err)
+		return
+	}
+	name := debugDumpPrefix + now.UTC().Format("20060102T150405.000Z") + ".json"
+	path := filepath.Join(s.config().DebugDumpDir, name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		logErrorf("写入调试转储失败: %v", err)
+		return
+	}
+	logDebugf("已写入调试转储: %s，共 %d 个事件", path, len(incidents))
+
+	if err := pruneDebugDumps(s.config().DebugDumpDir, s.config().DebugDumpKeep); err != nil {
+		logErrorf("清理调试转储失败: %v", err)
+	}
+}
+
+// 删除最旧的转储文件，只保留 keep 个。文件名中的时间戳保证按名称排序即按时间排序
+func pruneDebugDumps(dir string, keep int) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), debugDumpPrefix) && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for len(names) > keep {
+		if err := os.Remove(filepath.Join(dir, names[0])); err != nil {
+			return fmt.Errorf("删除 %s 失败: %v", names[0], err)
+		}
+		names = names[1:]
+	}
+	return nil
+}
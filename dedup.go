@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// notifiedIncident 记录已通知事件的名称指纹，用于跨状态页去重
+type notifiedIncident struct {
+	ID        string
+	Page      string
+	CreatedAt time.Time
+}
+
+// 计算归一化后的事件名称指纹：忽略大小写、标点和多余空白
+func normalizedNameHash(name string) string {
+	var b strings.Builder
+	for _, word := range nameWords(name) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// 判断变化是否是其他状态页上已通知事件的重复，调用方需持有锁。
+// 被判定为重复的事件后续更新也会被抑制
+func (s *Service) isDuplicateAcrossPages(incident Incident, changeType string) bool {
+	if !s.config().DedupAcrossPages {
+		return false
+	}
+	if s.duplicateOf == nil {
+		s.duplicateOf = make(map[string]string)
+		s.notifiedNames = make(map[string][]notifiedIncident)
+	}
+
+	if original, ok := s.duplicateOf[incident.ID]; ok {
+		logDebugf("抑制重复事件的通知 - ID: %s, 名称: %s, 与已通知事件 %s 重复", incident.ID, incident.Name, original)
+		return true
+	}
+
+	hash := normalizedNameHash(incident.Name)
+	window := time.Duration(s.config().DedupWindowMinutes) * time.Minute
+	if changeType == changeTypeNew {
+		for _, seen := range s.notifiedNames[hash] {
+			if seen.ID == incident.ID || seen.Page == incident.Page {
+				continue
+			}
+			diff := incident.CreatedAt.Sub(seen.CreatedAt)
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= window {
+				s.duplicateOf[incident.ID] = seen.ID
+				logDebugf("抑制重复事件的通知 - ID: %s, 名称: %s, 与状态页 %s 的事件 %s 名称相同且创建时间相差 %v",
+					incident.ID, incident.Name, seen.Page, seen.ID, diff)
+				return true
+			}
+		}
+	}
+
+	// 记录已通知的事件，并清理超出缓存保留期限的记录
+	retention := time.Duration(s.config().CacheRetentionDays) * 24 * time.Hour
+	entries := s.notifiedNames[hash][:0]
+	for _, seen := range s.notifiedNames[hash] {
+		if seen.ID != incident.ID && s.Now().Sub(seen.CreatedAt) <= retention {
+			entries = append(entries, seen)
+		}
+	}
+	s.notifiedNames[hash] = append(entries, notifiedIncident{ID: incident.ID, Page: incident.Page, CreatedAt: incident.CreatedAt})
+	return false
+}
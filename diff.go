@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// updateEdit 一条已知更新记录的内容被修改
+type updateEdit struct {
+	Update  Update
+	OldBody string
+}
+
+// 找出 ID 相同但内容被修改过的更新记录
+func editedUpdates(old, incident Incident) []updateEdit {
+	previous := make(map[string]string, len(old.IncidentUpdates))
+	for _, update := range old.IncidentUpdates {
+		previous[update.ID] = update.Body
+	}
+	var edits []updateEdit
+	for _, update := range incident.IncidentUpdates {
+		if body, ok := previous[update.ID]; ok && body != update.Body {
+			edits = append(edits, updateEdit{Update: update, OldBody: body})
+		}
+	}
+	return edits
+}
+
+// 按行比较新旧内容，基于最长公共子序列输出删除（-）和新增（+）的行，相同的行不输出
+func lineDiff(oldText, newText string) []string {
+	a := strings.Split(oldText, "\n")
+	b := strings.Split(newText, "\n")
+
+	// lcs[i][j] 为 a[i:] 和 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, "+ "+b[j])
+	}
+	return diff
+}
+
+// 生成被修改更新记录的差异说明
+func (s *Service) formatUpdateEdits(edits []updateEdit) string {
+	var out strings.Builder
+	for _, edit := range edits {
+		out.WriteString(fmt.Sprintf("更新内容已修改（%s [%s]）:\n\n",
+			s.formatTime(edit.Update.CreatedAt), edit.Update.Status))
+		oldBody := strings.ReplaceAll(edit.OldBody, "\r\n", "\n")
+		newBody := strings.ReplaceAll(edit.Update.Body, "\r\n", "\n")
+		for _, line := range lineDiff(oldBody, newBody) {
+			// 逐行清理，避免多行内容被 sanitizeUpdateBody 缩进
+			out.WriteString("> " + line[:2] + sanitizeUpdateBody(line[2:]) + "\n\n")
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// dispatchJob 提交到发送队列的一次发送操作
+type dispatchJob struct {
+	ctx  context.Context
+	send func() error
+	done chan error
+}
+
+// notificationDispatcher 所有外发通知的唯一出口：各代码路径提交的发送操作由单个 goroutine
+// 按提交顺序逐条执行，同一轮检查中的变更通知、每日报告和队列重投不会交错。
+// 各渠道自身的重试、去重和熔断仍在渠道内部进行，但都只会在这个 goroutine 中被调用
+type notificationDispatcher struct {
+	jobs    chan dispatchJob
+	limiter *rateLimiter // 所有渠道共用的发送限流，NOTIFY_RATE_LIMIT_PER_MINUTE 为 0 时为 nil
+}
+
+// 启动发送 goroutine，首次发送时调用
+func (s *Service) startDispatcher() {
+	d := &notificationDispatcher{jobs: make(chan dispatchJob)}
+	if s.config().NotifyRateLimit > 0 {
+		d.limiter = newRateLimiter(s.config().NotifyRateLimit)
+	}
+	s.dispatcher = d
+	go d.run()
+}
+
+func (d *notificationDispatcher) run() {
+	for job := range d.jobs {
+		if err := job.ctx.Err(); err != nil {
+			job.done <- fmt.Errorf("发送前已取消: %v", err)
+			continue
+		}
+		if d.limiter != nil {
+			waited, err := d.limiter.Wait(job.ctx)
+			if err != nil {
+				job.done <- fmt.Errorf("等待 NOTIFY_RATE_LIMIT_PER_MINUTE 限流时已取消: %v", err)
+				continue
+			}
+			if waited > 0 {
+				logDebugf("超过 NOTIFY_RATE_LIMIT_PER_MINUTE 限制，等待 %v 后发送", waited.Round(time.Millisecond))
+			}
+		}
+		job.done <- job.send()
+	}
+}
+
+// 将发送操作提交到发送队列并等待其完成，按提交顺序依次执行
+func (s *Service) dispatch(ctx context.Context, send func() error) error {
+	s.dispatchOnce.Do(s.startDispatcher)
+	job := dispatchJob{ctx: ctx, send: send, done: make(chan error, 1)}
+	select {
+	case s.dispatcher.jobs <- job:
+	case <-ctx.Done():
+		return fmt.Errorf("等待发送队列时已取消: %v", ctx.Err())
+	}
+	return <-job.done
+}
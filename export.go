@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 导出 CSV 的表头
+var csvExportHeader = []string{"id", "name", "status", "impact", "created", "resolved", "duration_minutes"}
+
+// 要导出的事件：配置了 DB_PATH 时查询历史存储，否则使用状态文件恢复的事件缓存。
+// 只包含回溯窗口内创建的事件，按创建时间倒序
+func (s *Service) exportIncidents() ([]Incident, error) {
+	start, now := s.lookbackStart(), s.Now()
+	if s.history != nil {
+		return s.history.QueryIncidents(start, now)
+	}
+	if s.config().StateFile == "" {
+		return nil, fmt.Errorf("-export-csv 需要配置 DB_PATH 或 STATE_FILE")
+	}
+
+	var incidents []Incident
+	s.mutex.RLock()
+	for _, incident := range s.lastIncidents {
+		if incident.CreatedAt.After(start) {
+			incidents = append(incidents, incident)
+		}
+	}
+	s.mutex.RUnlock()
+	sort.SliceStable(incidents, func(i, j int) bool {
+		return incidents[i].CreatedAt.After(incidents[j].CreatedAt)
+	})
+	return incidents, nil
+}
+
+// 按 CSV 格式写出事件，时间使用 RFC 3339 格式便于表格软件识别，未解决的事件解决时间和耗时为空
+func writeIncidentsCSV(w io.Writer, incidents []Incident) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvExportHeader); err != nil {
+		return err
+	}
+	for _, incident := range incidents {
+		resolved, duration := "", ""
+		if resolvedAt := incident.resolvedTime(); !resolvedAt.IsZero() {
+			resolved = resolvedAt.UTC().Format(time.RFC3339)
+		}
+		if d, ok := incident.resolutionDuration(); ok {
+			duration = strconv.FormatFloat(d.Minutes(), 'f', 0, 64)
+		}
+		record := []string{
+			incident.ID,
+			incident.Name,
+			incident.Status,
+			incident.Impact,
+			incident.CreatedAt.UTC().Format(time.RFC3339),
+			resolved,
+			duration,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// -export-csv 模式：将回溯窗口内的事件导出为 CSV 文件后退出，path 为 - 时输出到标准输出
+func (s *Service) runExportCSV(path string) error {
+	incidents, err := s.exportIncidents()
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("创建导出文件失败: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := writeIncidentsCSV(out, incidents); err != nil {
+		return fmt.Errorf("写入 CSV 失败: %v", err)
+	}
+	logInfof("已导出 %d 个事件到 %s", len(incidents), path)
+	return nil
+}
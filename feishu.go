@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// feishuResponse 飞书机器人接口的响应
+type feishuResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// feishuNotifier 飞书自定义机器人通知渠道
+type feishuNotifier struct {
+	webhook          string
+	secret           string
+	maxResponseBytes int64
+	now              func() time.Time // 用于生成签名时间戳
+}
+
+func newFeishuNotifier(webhook, secret string, maxResponseBytes int64, now func() time.Time) *feishuNotifier {
+	return &feishuNotifier{webhook: webhook, secret: secret, maxResponseBytes: maxResponseBytes, now: now}
+}
+
+func (f *feishuNotifier) Name() string {
+	return "feishu"
+}
+
+func (f *feishuNotifier) Send(ctx context.Context, n Notification) error {
+	logDebugf("准备发送飞书通知 - 标题: %s", n.Title)
+
+	message := map[string]interface{}{
+		"msg_type": "interactive",
+		"card": map[string]interface{}{
+			"config": map[string]interface{}{"wide_screen_mode": true},
+			"header": map[string]interface{}{
+				"title": map[string]string{"tag": "plain_text", "content": n.Title},
+			},
+			"elements": []interface{}{
+				map[string]interface{}{
+					"tag":  "div",
+					"text": map[string]string{"tag": "lark_md", "content": toFeishuMarkdown(n.Content)},
+				},
+			},
+		},
+	}
+	if f.secret != "" {
+		timestamp := strconv.FormatInt(f.now().Unix(), 10)
+		message["timestamp"] = timestamp
+		message["sign"] = generateFeishuSign(timestamp, f.secret)
+	}
+
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("生成飞书消息 JSON 失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.webhook, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("创建飞书请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送飞书 HTTP 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := readLimited(resp.Body, f.maxResponseBytes)
+	if err != nil {
+		return fmt.Errorf("读取飞书响应失败: %v", err)
+	}
+	logDebugf("飞书响应: HTTP状态码=%d, 响应内容=%s", resp.StatusCode, string(respBody))
+
+	var result feishuResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("解析飞书响应失败: %v", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("飞书返回错误: code=%d, msg=%s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// 飞书签名：以 timestamp + "\n" + secret 作为 HMAC 密钥，对空消息计算 SHA256
+func generateFeishuSign(timestamp, secret string) string {
+	stringToSign := timestamp + "\n" + secret
+	h := hmac.New(sha256.New, []byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// 将钉钉风格的 Markdown 转换为飞书 lark_md，lark_md 不支持标题语法，改为加粗
+func toFeishuMarkdown(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, "#")
+		if trimmed != line && strings.HasPrefix(trimmed, " ") {
+			lines[i] = "**" + strings.TrimSpace(trimmed) + "**"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+// 飞书签名校验：以 timestamp + "\n" + secret 作为密钥对空消息做 HMAC-SHA256 后 Base64 编码。
+// 期望值按该算法独立计算，签名实现改变时所有飞书消息都会被拒绝
+func TestGenerateFeishuSign(t *testing.T) {
+	tests := []struct {
+		timestamp, secret string
+		want              string
+	}{
+		{timestamp: "1599360473", secret: "SECxyz", want: "5uPTQ6AhsCWqk+iix6foSKSocxGEvxldJYlDIzWUs5Q="},
+		{timestamp: "1599360473", secret: "", want: "bTgbk2pXnbLSbeiYuPU4Xb5u0Ucy6Ht2YrCPIEpaI58="},
+	}
+	for _, tt := range tests {
+		if got := generateFeishuSign(tt.timestamp, tt.secret); got != tt.want {
+			t.Errorf("generateFeishuSign(%q, %q) = %q, 期望 %q", tt.timestamp, tt.secret, got, tt.want)
+		}
+	}
+}
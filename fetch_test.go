@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 状态页返回 304 且没有响应内容时沿用缓存的事件，不报告任何变化
+func TestFetchNotModifiedKeepsCachedIncidents(t *testing.T) {
+	page := newFakeStatusPage(t)
+	page.setIncidents(t, `"v1"`, testIncident("inc1", "investigating", "major", time.Hour))
+
+	s := newTestService(t, "STATUS_PAGE_URL="+page.URL+"\n")
+	recorder := &recordingNotifier{}
+	setTestNotifiers(s, recorder)
+	ctx := context.Background()
+
+	if _, err := s.fetchAndProcessIncidents(ctx); err != nil {
+		t.Fatalf("首次获取失败: %v", err)
+	}
+	cached := s.lastIncidents["inc1"]
+	if cached.ID == "" {
+		t.Fatalf("首次获取后缓存中没有事件: %+v", s.lastIncidents)
+	}
+	if got := len(recorder.notifications()); got != 1 {
+		t.Fatalf("首次获取应只发送启动通知，实际 %d 条", got)
+	}
+
+	// 即使接口内容已变化，ETag 未变时服务器返回 304，服务不应读取新内容
+	page.setBody(`"v1"`, []byte("not json"))
+	changes, err := s.fetchAndProcessIncidents(ctx)
+	if err != nil {
+		t.Fatalf("304 响应被当作错误: %v", err)
+	}
+	_, notModified, ifNoneMatch := page.stats()
+	if ifNoneMatch != `"v1"` {
+		t.Errorf("条件请求的 If-None-Match = %q, 期望 %q", ifNoneMatch, `"v1"`)
+	}
+	if notModified != 1 {
+		t.Fatalf("服务器返回 304 的次数 = %d, 期望 1", notModified)
+	}
+	if changes != 0 {
+		t.Errorf("304 响应报告了 %d 个变化", changes)
+	}
+	if got := len(recorder.notifications()); got != 1 {
+		t.Errorf("304 响应后发送了额外的通知，共 %d 条", got)
+	}
+	if len(s.lastIncidents) != 1 || !reflect.DeepEqual(s.lastIncidents["inc1"], cached) {
+		t.Errorf("304 响应后缓存被修改: %+v", s.lastIncidents)
+	}
+	if entry := s.pageCache[page.URL]; len(entry.incidents) != 1 || entry.etag != `"v1"` {
+		t.Errorf("304 响应后状态页缓存被修改: %+v", entry)
+	}
+}
+
+// 接口返回重复 ID 的事件时只跟踪一个事件，只发送一条通知
+func TestFetchDuplicateIncidentIDs(t *testing.T) {
+	page := newFakeStatusPage(t)
+	page.setIncidents(t, "")
+
+	s := newTestService(t, "STATUS_PAGE_URL="+page.URL+"\nSEND_STARTUP_NOTIFICATION=false\n")
+	recorder := &recordingNotifier{}
+	setTestNotifiers(s, recorder)
+	ctx := context.Background()
+	if _, err := s.fetchAndProcessIncidents(ctx); err != nil {
+		t.Fatalf("首次获取失败: %v", err)
+	}
+
+	older := testIncident("dup", "investigating", "major", time.Hour)
+	newer := older
+	newer.Status = "identified"
+	newer.UpdatedAt = older.UpdatedAt.Add(10 * time.Minute)
+	page.setIncidents(t, "", older, newer)
+
+	if _, err := s.fetchAndProcessIncidents(ctx); err != nil {
+		t.Fatalf("获取失败: %v", err)
+	}
+	if len(s.lastIncidents) != 1 {
+		t.Fatalf("跟踪的事件数量 = %d, 期望 1: %+v", len(s.lastIncidents), s.lastIncidents)
+	}
+	if got := s.lastIncidents["dup"].Status; got != "identified" {
+		t.Errorf("保留的事件状态 = %q, 期望 updated_at 较新的 identified", got)
+	}
+	notifications := recorder.notifications()
+	if len(notifications) != 1 {
+		t.Fatalf("发送的通知数量 = %d, 期望 1", len(notifications))
+	}
+	if events := notifications[0].Events; len(events) != 1 || events[0].Incident.ID != "dup" || events[0].ChangeType != changeTypeNew {
+		t.Errorf("通知中的事件 = %+v, 期望一个新事件 dup", events)
+	}
+}
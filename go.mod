@@ -1,3 +1,5 @@
 module cf-status
 
-go 1.20 
\ No newline at end of file
+go 1.20
+
+require github.com/mattn/go-sqlite3 v1.14.24
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// historyDriver 历史存储使用的 database/sql 驱动名，由带 sqlite 构建标签的 history_sqlite.go 注册
+var historyDriver string
+
+// historyStore 基于 SQLite 的事件历史存储，与用于变化检测的 lastIncidents 缓存相互独立
+type historyStore struct {
+	db *sql.DB
+}
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS incidents (
+	id            TEXT PRIMARY KEY,
+	page          TEXT NOT NULL DEFAULT '',
+	name          TEXT NOT NULL,
+	status        TEXT NOT NULL,
+	impact        TEXT NOT NULL,
+	shortlink     TEXT NOT NULL DEFAULT '',
+	created_at    TEXT NOT NULL,
+	updated_at    TEXT NOT NULL,
+	monitoring_at TEXT NOT NULL DEFAULT '',
+	resolved_at   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_incidents_created_at ON incidents(created_at);
+CREATE TABLE IF NOT EXISTS updates (
+	id          TEXT PRIMARY KEY,
+	incident_id TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	body        TEXT NOT NULL,
+	created_at  TEXT NOT NULL,
+	updated_at  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_updates_incident_id ON updates(incident_id);
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+// 打开历史存储并初始化表结构
+func openHistoryStore(path string) (*historyStore, error) {
+	if historyDriver == "" {
+		return nil, fmt.Errorf("当前构建未包含 SQLite 支持，请使用 -tags sqlite 重新编译")
+	}
+	db, err := sql.Open(historyDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("打开历史数据库失败: %v", err)
+	}
+	// SQLite 只允许单个写入者，使用单连接避免锁冲突
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化历史数据库失败: %v", err)
+	}
+	if err := migrateDBTimes(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("迁移历史数据库时间格式失败: %v", err)
+	}
+	return &historyStore{db: db}, nil
+}
+
+func (h *historyStore) Close() error {
+	return h.db.Close()
+}
+
+// 数据库中的时间格式：UTC、固定 9 位小数的 RFC 3339。RFC3339Nano 会省略末尾的 0，
+// 长度不固定的字符串按字典序比较时顺序错误（如 "05.87Z" 排在 "05.872Z" 之后）
+const dbTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// 时间统一以固定宽度的 UTC 字符串存储，便于按字符串比较范围
+func formatDBTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(dbTimeLayout)
+}
+
+func parseDBTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	// RFC3339Nano 同时兼容固定宽度和旧版本写入的不定长小数
+	t, _ := time.Parse(time.RFC3339Nano, value)
+	return t
+}
+
+// 旧版本以 RFC3339Nano 写入的时间长度不固定，打开数据库时统一改写为固定宽度格式，只执行一次
+func migrateDBTimes(db *sql.DB) error {
+	var layout string
+	err := db.QueryRow(`SELECT value FROM meta WHERE key = 'time_layout'`).Scan(&layout)
+	if err == nil && layout == dbTimeLayout {
+		return nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range []struct {
+		name    string
+		columns []string
+	}{
+		{"incidents", []string{"created_at", "updated_at", "monitoring_at", "resolved_at"}},
+		{"updates", []string{"created_at", "updated_at"}},
+	} {
+		for _, column := range table.columns {
+			if err := rewriteDBTimes(tx, fmt.Sprintf("SELECT id, %s FROM %s", column, table.name),
+				fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", table.name, column)); err != nil {
+				return fmt.Errorf("迁移 %s.%s 失败: %v", table.name, column, err)
+			}
+		}
+	}
+	if err := rewriteDBTimes(tx, `SELECT key, value FROM meta WHERE key = 'last_seen'`,
+		`UPDATE meta SET value = ? WHERE key = ?`); err != nil {
+		return fmt.Errorf("迁移 meta.last_seen 失败: %v", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES ('time_layout', ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value`, dbTimeLayout); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// 读取 query 返回的 (键, 时间) 行，按固定宽度格式重新写入
+func rewriteDBTimes(tx *sql.Tx, query, update string) error {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return err
+	}
+	rewritten := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		if formatted := formatDBTime(parseDBTime(value)); formatted != value {
+			rewritten[key] = formatted
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for key, value := range rewritten {
+		if _, err := tx.Exec(update, value, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 按 ID 写入或更新事件及其更新记录
+func (h *historyStore) SaveIncidents(incidents []Incident) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, incident := range incidents {
+		if _, err := tx.Exec(`
+INSERT INTO incidents (id, page, name, status, impact, shortlink, created_at, updated_at, monitoring_at, resolved_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+	page = excluded.page, name = excluded.name, status = excluded.status, impact = excluded.impact,
+	shortlink = excluded.shortlink, created_at = excluded.created_at, updated_at = excluded.updated_at,
+	monitoring_at = excluded.monitoring_at, resolved_at = excluded.resolved_at`,
+			incident.ID, incident.Page, incident.Name, incident.Status, incident.Impact, incident.Shortlink,
+			formatDBTime(incident.CreatedAt), formatDBTime(incident.UpdatedAt),
+			formatDBTime(incident.MonitoringAt), formatDBTime(incident.ResolvedAt)); err != nil {
+			return fmt.Errorf("写入事件 %s 失败: %v", incident.ID, err)
+		}
+
+		for _, update := range incident.IncidentUpdates {
+			if _, err := tx.Exec(`
+INSERT INTO updates (id, incident_id, status, body, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+	incident_id = excluded.incident_id, status = excluded.status, body = excluded.body,
+	created_at = excluded.created_at, updated_at = excluded.updated_at`,
+				update.ID, incident.ID, update.Status, update.Body,
+				formatDBTime(update.CreatedAt), formatDBTime(update.UpdatedAt)); err != nil {
+				return fmt.Errorf("写入事件更新 %s 失败: %v", update.ID, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// 记录最近一次成功检查的时间，用于重启后判断监控离线的时段
+func (h *historyStore) SetLastSeen(t time.Time) error {
+	_, err := h.db.Exec(`INSERT INTO meta (key, value) VALUES ('last_seen', ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value`, formatDBTime(t))
+	return err
+}
+
+// 读取最近一次成功检查的时间，从未记录时返回零值
+func (h *historyStore) LastSeen() (time.Time, error) {
+	var value string
+	err := h.db.QueryRow(`SELECT value FROM meta WHERE key = 'last_seen'`).Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseDBTime(value), nil
+}
+
+// 查询事件在历史存储中的更新时间，事件不存在时返回 false
+func (h *historyStore) IncidentUpdatedAt(id string) (time.Time, bool, error) {
+	var value string
+	err := h.db.QueryRow(`SELECT updated_at FROM incidents WHERE id = ?`, id).Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return parseDBTime(value), true, nil
+}
+
+// 查询创建时间在 [from, to) 范围内的事件，按创建时间倒序
+func (h *historyStore) QueryIncidents(from, to time.Time) ([]Incident, error) {
+	rows, err := h.db.Query(`
+SELECT id, page, name, status, impact, shortlink, created_at, updated_at, monitoring_at, resolved_at
+FROM incidents WHERE created_at >= ? AND created_at < ? ORDER BY created_at DESC`,
+		formatDBTime(from), formatDBTime(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []Incident
+	for rows.Next() {
+		var incident Incident
+		var createdAt, updatedAt, monitoringAt, resolvedAt string
+		if err := rows.Scan(&incident.ID, &incident.Page, &incident.Name, &incident.Status, &incident.Impact,
+			&incident.Shortlink, &createdAt, &updatedAt, &monitoringAt, &resolvedAt); err != nil {
+			return nil, err
+		}
+		incident.CreatedAt = parseDBTime(createdAt)
+		incident.UpdatedAt = parseDBTime(updatedAt)
+		incident.MonitoringAt = parseDBTime(monitoringAt)
+		incident.ResolvedAt = parseDBTime(resolvedAt)
+		incidents = append(incidents, incident)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range incidents {
+		updates, err := h.queryUpdates(incidents[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		incidents[i].IncidentUpdates = updates
+	}
+	return incidents, nil
+}
+
+func (h *historyStore) queryUpdates(incidentID string) ([]Update, error) {
+	rows, err := h.db.Query(`
+SELECT id, status, body, created_at, updated_at FROM updates
+WHERE incident_id = ? ORDER BY created_at DESC`, incidentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var updates []Update
+	for rows.Next() {
+		var update Update
+		var createdAt, updatedAt string
+		if err := rows.Scan(&update.ID, &update.Status, &update.Body, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		update.CreatedAt = parseDBTime(createdAt)
+		update.UpdatedAt = parseDBTime(updatedAt)
+		updates = append(updates, update)
+	}
+	return updates, rows.Err()
+}
@@ -0,0 +1,11 @@
+//go:build sqlite
+
+package main
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	historyDriver = "sqlite3"
+}
@@ -0,0 +1,113 @@
+//go:build sqlite
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryStoreUpsertAndQuery(t *testing.T) {
+	store, err := openHistoryStore(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	incident := func(id string, createdAt time.Time, status string) Incident {
+		return Incident{ID: id, Name: "Incident " + id, Status: status, Impact: "minor",
+			CreatedAt: createdAt, UpdatedAt: createdAt,
+			IncidentUpdates: []Update{{ID: id + "-u1", Status: status, Body: status, CreatedAt: createdAt, UpdatedAt: createdAt}}}
+	}
+	if err := store.SaveIncidents([]Incident{
+		incident("early", day.Add(-time.Hour), "resolved"),
+		incident("first", day.Add(2*time.Hour), "investigating"),
+		incident("second", day.Add(5*time.Hour), "investigating"),
+		incident("late", day.Add(24*time.Hour), "investigating"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 同一 ID 再次写入时更新已有记录
+	updated := incident("first", day.Add(2*time.Hour), "resolved")
+	updated.IncidentUpdates = append(updated.IncidentUpdates,
+		Update{ID: "first-u2", Status: "resolved", Body: "resolved", CreatedAt: day.Add(3 * time.Hour), UpdatedAt: day.Add(3 * time.Hour)})
+	if err := store.SaveIncidents([]Incident{updated}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.QueryIncidents(day, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "second" || got[1].ID != "first" {
+		t.Fatalf("QueryIncidents 返回 %+v, 期望按创建时间倒序的 second、first", got)
+	}
+	first := got[1]
+	if first.Status != "resolved" || !first.CreatedAt.Equal(day.Add(2*time.Hour)) {
+		t.Errorf("更新后的事件 = %+v", first)
+	}
+	if len(first.IncidentUpdates) != 2 || first.IncidentUpdates[0].ID != "first-u2" {
+		t.Errorf("事件更新 = %+v, 期望按时间倒序的两条更新", first.IncidentUpdates)
+	}
+}
+
+func TestQueryIncidentsRangeWithSubsecondTimes(t *testing.T) {
+	store, err := openHistoryStore(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	from := time.Date(2024, 3, 1, 12, 0, 5, 872000000, time.UTC)
+	incidents := []Incident{
+		{ID: "before", Name: "before", Status: "resolved", Impact: "minor", CreatedAt: from.Add(-2 * time.Millisecond)},
+		{ID: "exact", Name: "exact", Status: "resolved", Impact: "minor", CreatedAt: from},
+		{ID: "whole-second", Name: "whole-second", Status: "resolved", Impact: "minor", CreatedAt: from.Truncate(time.Second).Add(time.Second)},
+	}
+	if err := store.SaveIncidents(incidents); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.QueryIncidents(from, from.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, incident := range got {
+		ids = append(ids, incident.ID)
+	}
+	if len(ids) != 2 || ids[0] != "whole-second" || ids[1] != "exact" {
+		t.Errorf("QueryIncidents = %v, 期望 [whole-second exact]", ids)
+	}
+}
+
+func TestOpenHistoryStoreMigratesLegacyTimes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := openHistoryStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(`INSERT INTO incidents (id, name, status, impact, created_at, updated_at)
+VALUES ('legacy', 'legacy', 'resolved', 'minor', '2024-03-01T12:00:05.87Z', '2024-03-01T12:00:05Z')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(`DELETE FROM meta WHERE key = 'time_layout'`); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = openHistoryStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var createdAt, updatedAt string
+	if err := store.db.QueryRow(`SELECT created_at, updated_at FROM incidents WHERE id = 'legacy'`).Scan(&createdAt, &updatedAt); err != nil {
+		t.Fatal(err)
+	}
+	if createdAt != "2024-03-01T12:00:05.870000000Z" || updatedAt != "2024-03-01T12:00:05.000000000Z" {
+		t.Errorf("迁移后的时间 = %q, %q", createdAt, updatedAt)
+	}
+}
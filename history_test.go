@@ -0,0 +1,54 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// 未使用 -tags sqlite 构建时配置 DB_PATH 应返回明确的错误
+func TestOpenHistoryStoreWithoutDriver(t *testing.T) {
+	if historyDriver != "" {
+		t.Skip("当前构建包含 SQLite 支持")
+	}
+	if _, err := openHistoryStore(filepath.Join(t.TempDir(), "history.db")); err == nil {
+		t.Error("没有 SQLite 驱动时 openHistoryStore 应返回错误")
+	}
+}
+
+func TestFormatDBTimeSortsChronologically(t *testing.T) {
+	base := time.Date(2024, 3, 1, 12, 0, 5, 0, time.UTC)
+	times := []time.Time{
+		base,
+		base.Add(870 * time.Millisecond),
+		base.Add(872 * time.Millisecond),
+		base.Add(872*time.Millisecond + 1),
+		base.Add(time.Second),
+	}
+	formatted := make([]string, len(times))
+	for i, tm := range times {
+		formatted[i] = formatDBTime(tm)
+	}
+	if !sort.StringsAreSorted(formatted) {
+		t.Fatalf("格式化后的时间未按字典序排列: %v", formatted)
+	}
+	for i, value := range formatted {
+		if len(value) != len(formatted[0]) {
+			t.Errorf("时间 %q 长度不固定", value)
+		}
+		if got := parseDBTime(value); !got.Equal(times[i]) {
+			t.Errorf("parseDBTime(%q) = %v, 期望 %v", value, got, times[i])
+		}
+	}
+}
+
+func TestParseDBTimeAcceptsLegacyValues(t *testing.T) {
+	want := time.Date(2024, 3, 1, 12, 0, 5, 870000000, time.UTC)
+	if got := parseDBTime("2024-03-01T12:00:05.87Z"); !got.Equal(want) {
+		t.Errorf("parseDBTime 旧格式 = %v, 期望 %v", got, want)
+	}
+	if got := parseDBTime(""); !got.IsZero() {
+		t.Errorf("parseDBTime 空字符串 = %v, 期望零值", got)
+	}
+}
@@ -1,7 +1,10 @@
 #!/bin/bash
 
-# 编译程序
-go build -o cf-status
+# 编译程序，注入版本和构建信息（cf-status -version 可查看）
+VERSION=$(git describe --tags --always --dirty 2>/dev/null || echo dev)
+COMMIT=$(git rev-parse --short HEAD 2>/dev/null || echo unknown)
+BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+go build -ldflags "-X main.version=${VERSION} -X main.commit=${COMMIT} -X main.date=${BUILD_DATE}" -o cf-status
 
 # 创建必要的目录
 sudo mkdir -p /etc/cf-status
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 时间字段不是 RFC3339 格式时依次尝试的备用格式
+var fallbackTimeLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+}
+
+// lenientTime 宽松解析的时间类型：先按 RFC3339 解析，失败时尝试备用格式，
+// 全部失败时保持零值而不是让整个响应解析失败
+type lenientTime struct {
+	time.Time
+	raw    string // 非 RFC3339 格式的原始值
+	layout string // 解析成功时使用的备用格式，为空表示无法解析
+}
+
+func (t *lenientTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.raw = string(data)
+		return nil
+	}
+	if value == "" {
+		return nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		t.Time = parsed
+		return nil
+	}
+
+	t.raw = value
+	for _, layout := range fallbackTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			t.Time = parsed
+			t.layout = layout
+			return nil
+		}
+	}
+	return nil
+}
+
+// 对使用了备用格式或无法解析的字段输出警告
+func (t lenientTime) warn(owner, id, field string) {
+	if t.raw == "" {
+		return
+	}
+	if t.layout != "" {
+		logWarnf("警告: %s %s 的 %s 不是 RFC3339 格式，已按备用格式解析: %q", owner, id, field, t.raw)
+		return
+	}
+	logWarnf("警告: %s %s 的 %s 无法解析，已置为空: %q", owner, id, field, t.raw)
+}
+
+// 使用宽松的时间解析，单个时间字段格式错误不会导致整个响应被丢弃
+func (i *Incident) UnmarshalJSON(data []byte) error {
+	type plain Incident
+	aux := struct {
+		*plain
+		CreatedAt    lenientTime `json:"created_at"`
+		UpdatedAt    lenientTime `json:"updated_at"`
+		MonitoringAt lenientTime `json:"monitoring_at"`
+		ResolvedAt   lenientTime `json:"resolved_at"`
+	}{plain: (*plain)(i)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	i.CreatedAt = aux.CreatedAt.Time
+	i.UpdatedAt = aux.UpdatedAt.Time
+	i.MonitoringAt = aux.MonitoringAt.Time
+	i.ResolvedAt = aux.ResolvedAt.Time
+	aux.CreatedAt.warn("事件", i.ID, "created_at")
+	aux.UpdatedAt.warn("事件", i.ID, "updated_at")
+	aux.MonitoringAt.warn("事件", i.ID, "monitoring_at")
+	aux.ResolvedAt.warn("事件", i.ID, "resolved_at")
+	return nil
+}
+
+// 使用宽松的时间解析，规则同 Incident
+func (u *Update) UnmarshalJSON(data []byte) error {
+	type plain Update
+	aux := struct {
+		*plain
+		CreatedAt lenientTime `json:"created_at"`
+		UpdatedAt lenientTime `json:"updated_at"`
+	}{plain: (*plain)(u)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	u.CreatedAt = aux.CreatedAt.Time
+	u.UpdatedAt = aux.UpdatedAt.Time
+	aux.CreatedAt.warn("事件更新", u.ID, "created_at")
+	aux.UpdatedAt.warn("事件更新", u.ID, "updated_at")
+	return nil
+}
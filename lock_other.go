@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// 非 Unix 平台不支持 flock，不加锁，多个实例共用去重文件时只能尽力而为
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
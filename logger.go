@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志输出格式
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// 日志级别，低于 LOG_LEVEL 的日志不输出
+const (
+	logLevelDebug = "debug"
+	logLevelInfo  = "info"
+	logLevelWarn  = "warn"
+	logLevelError = "error"
+)
+
+var logLevelRank = map[string]int{
+	logLevelDebug: 0,
+	logLevelInfo:  1,
+	logLevelWarn:  2,
+	logLevelError: 3,
+}
+
+// logFields 结构化日志的附加字段
+type logFields map[string]interface{}
+
+var (
+	logMutex    sync.Mutex
+	logFormat             = logFormatText
+	logMinLevel           = logLevelInfo
+	logOutput   io.Writer = os.Stderr
+)
+
+// 修改最低日志级别，用于重新加载配置
+func setLogLevel(level string) {
+	logMutex.Lock()
+	logMinLevel = level
+	logMutex.Unlock()
+}
+
+// jsonLineWriter 将标准库 log 的每一行输出包装成 JSON 行
+type jsonLineWriter struct{}
+
+func (jsonLineWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	writeJSONLog("info", "", nil, msg)
+	return len(p), nil
+}
+
+// 根据配置初始化日志输出
+func setupLogging(format, level string, out io.Writer) {
+	logMutex.Lock()
+	logFormat = format
+	logMinLevel = level
+	logOutput = out
+	logMutex.Unlock()
+
+	if format == logFormatJSON {
+		log.SetFlags(0)
+		log.SetOutput(jsonLineWriter{})
+		return
+	}
+	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
+	log.SetOutput(out)
+}
+
+// logEvent 输出带结构化字段的日志，text 模式下与 log.Printf 输出一致。
+// 级别低于 LOG_LEVEL 时直接丢弃
+func logEvent(level, component string, fields logFields, format string, args ...interface{}) {
+	logMutex.Lock()
+	jsonMode := logFormat == logFormatJSON
+	enabled := logLevelRank[level] >= logLevelRank[logMinLevel]
+	logMutex.Unlock()
+
+	if !enabled {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if !jsonMode {
+		log.Print(msg)
+		return
+	}
+	writeJSONLog(level, component, fields, msg)
+}
+
+func logDebugf(format string, args ...interface{}) {
+	logEvent(logLevelDebug, "", nil, format, args...)
+}
+
+func logInfof(format string, args ...interface{}) {
+	logEvent(logLevelInfo, "", nil, format, args...)
+}
+
+func logWarnf(format string, args ...interface{}) {
+	logEvent(logLevelWarn, "", nil, format, args...)
+}
+
+func logErrorf(format string, args ...interface{}) {
+	logEvent(logLevelError, "", nil, format, args...)
+}
+
+// logFatalf 以 error 级别记录日志后以退出码 1 结束进程。
+// 不使用 log.Fatalf，JSON 模式下标准库 log 的输出会被标记为 info 级别
+func logFatalf(format string, args ...interface{}) {
+	logErrorf(format, args...)
+	os.Exit(1)
+}
+
+func writeJSONLog(level, component string, fields logFields, msg string) {
+	entry := make(map[string]interface{}, len(fields)+4)
+	for k, v := range fields {
+		entry[k] = v
+	}
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["msg"] = msg
+	if component != "" {
+		entry["component"] = component
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data = []byte(fmt.Sprintf(`{"level":"error","msg":"日志序列化失败: %v"}`, err))
+	}
+
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	logOutput.Write(append(data, '\n'))
+}
+
+// rotatingFile 追加写入的日志文件，超过大小上限时重命名为 .1 后重新打开
+type rotatingFile struct {
+	mutex   sync.Mutex
+	path    string
+	maxSize int64 // 单个文件的最大字节数，0 表示不轮转
+	file    *os.File
+	size    int64
+}
+
+// 以追加模式打开日志文件，maxSizeMB 为 0 时不轮转
+func openRotatingFile(path string, maxSizeMB int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: int64(maxSizeMB) * 1024 * 1024}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取日志文件信息失败: %v", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			// 轮转失败时继续写入原文件，避免丢失日志
+			fmt.Fprintf(os.Stderr, "日志文件轮转失败: %v\n", err)
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// 将当前文件重命名为 .1（覆盖上一次的备份）并重新打开
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		if openErr := r.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.file.Close()
+}
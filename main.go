@@ -3,44 +3,255 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"html"
+	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 // Config 配置结构体
 type Config struct {
-	CheckIntervalMinutes int
-	DailyReportUTCHour   int
-	MaxIncidents         int // 添加最大事件数量配置
-	DingtalkWebhookToken string
-	DingtalkSecret       string
+	CheckIntervalMinutes         int
+	ActiveCheckIntervalMinutes   int // 存在未解决事件时使用的检查间隔，0 表示不启用
+	PollJitterSeconds            int // 每轮检查间隔额外增加的随机偏移上限（秒），0 表示不启用
+	DailyReportUTCHour           int
+	DailyReportHours             []int // 每日报告的发送时间（UTC 小时），未配置 DAILY_REPORT_HOURS 时为 DAILY_REPORT_UTC_HOUR
+	MaxIncidents                 int   // 添加最大事件数量配置
+	DingtalkWebhookToken         string
+	DingtalkSecret               string
+	DingtalkCriticalWebhook      string // critical 事件使用的钉钉机器人 access_token，为空时使用默认机器人
+	DingtalkCriticalSecret       string
+	DingtalkInfoWebhook          string // 非 critical 事件使用的钉钉机器人 access_token，为空时使用默认机器人
+	DingtalkInfoSecret           string
+	DingtalkRoutes               []dingtalkRoute // 按关键词路由到不同钉钉机器人的规则，优先于按影响程度路由
+	DingtalkAuthFailureThreshold int             // 钉钉连续认证失败多少次后告警并改用备用渠道
+	DingtalkFallbackNotifier     string          // 钉钉认证持续失败时使用的备用通知渠道
+	DingtalkSecurityMode         string          // 钉钉机器人安全设置: sign 或 keyword
+	DingtalkKeyword              string          // keyword 模式下消息必须包含的关键词
+	DingtalkBaseURL              string          // 钉钉开放接口地址，通过企业内部网关转发时修改
+	MinImpactLevel               string          // 只通知不低于该影响程度的事件
+	NotifyAllNewIncidents        bool            // 新事件不受 MIN_IMPACT_LEVEL 过滤，后续更新仍然过滤
+	NotifyStatuses               []string        // 只在事件处于这些状态时通知，为空时全部通知
+	ConfirmCriticalAlerts        bool            // 发送高优先级告警前是否重新获取一次状态页确认
+	DuplicateIncidentPolicy      string          // 一次获取中同一事件 ID 出现多次时保留哪一条: latest 或 first
+	SLAImpactLevels              []string        // 计入不可用时间的事件影响程度
+	UserAgent                    string          // 请求状态页时使用的 User-Agent
+	RequestHeaders               http.Header     // 请求状态页时附加的请求头
+	LongIncidentThresholdMinutes int             // 事件超过该时长仍未解决时发送提醒，0 表示不提醒
+	PostResolutionQuietMinutes   int             // 事件解决后该时长内的后续更新只缓存不通知，0 表示不启用
+	LogFormat                    string          // 日志格式: text 或 json
+	LogLevel                     string          // 最低日志级别: debug、info、warn 或 error
+	LogFile                      string          // 日志文件路径，为空时输出到 stderr
+	DebugDumpDir                 string          // 调试转储目录，配置后每轮检查将解析后的事件写入 JSON 文件
+	DebugDumpKeep                int             // 最多保留的调试转储文件数
+	MaxResponseBytes             int64           // 读取 HTTP 响应内容的最大字节数
+	LogMaxSizeMB                 int             // 日志文件超过该大小（MB）时轮转，0 表示不轮转
+	Notifiers                    []string        // 启用的通知渠道
+	WebhookURL                   string
+	WebhookToken                 string
+	WebhookSigningSecret         string            // 通用 Webhook 请求体的 HMAC-SHA256 签名密钥，为空时不签名
+	SendStartupNotification      bool              // 是否发送首次运行通知
+	StartupIncludeResolved       bool              // 首次运行通知是否同时列出回溯窗口内已解决的事件
+	NotifyOldActiveIncidents     bool              // 创建时间早于回溯窗口的未解决事件是否照常参与变化检测
+	WarmupChecks                 int               // 冷启动时先进行的只填充缓存、不发送通知的成功检查次数
+	ImpactLabels                 map[string]string // 影响程度 -> 通知中展示的名称，如 critical -> P1
+	StatusPageURL                string            // 状态页地址
+	StatusPages                  []string          // 监控的状态页列表
+	ProbeStatusPages             bool              // 启动时是否探测各状态页是否兼容 Statuspage API
+	PageLocales                  map[string]string // 状态页地址 -> 请求本地化内容时使用的语言
+	PageNames                    map[string]string // 状态页地址 -> STATUS_PAGES 中配置的显示名称
+	PageLocaleQueryParam         string            // 除 Accept-Language 外，以该查询参数传递语言，为空时不附加
+	FetchConcurrency             int               // 并发获取状态页的数量
+	NotifyRetryCount             int               // 通知发送失败后的重试次数
+	NotifyQueueFile              string            // 钉钉通知投递队列文件路径，为空时不持久化发送失败的通知
+	NotifyQueueMaxAgeHours       int               // 投递队列中的通知最长保留小时数
+	NotifyDedupFile              string            // 变更通知去重记录文件，多个实例可共用，为空时不去重
+	NotifyDedupTTLMinutes        int               // 去重记录的保留分钟数
+	QuietHoursStart              int               // 静默时段开始小时（UTC），-1 表示未启用
+	QuietHoursEnd                int               // 静默时段结束小时（UTC），-1 表示未启用
+	CacheRetentionDays           int               // 事件缓存保留天数
+	StateFile                    string            // 状态文件路径，为空时不持久化
+	UpdateDisplayMode            string            // 变更通知中更新历史的展示模式: full 或 latest
+	ShowUpdateHistory            bool              // 实时通知中是否展示更新历史，关闭时已解决的事件只展示解决时的更新
+	MaxUpdatesInDetail           int               // 事件详情中最多展示的更新条数，0 表示不限制
+	MaxConsecutiveFailures       int               // 连续获取失败多少次后发送降级告警
+	MonitorComponents            bool              // 是否监控组件状态
+	WatchComponents              []string          // 订阅的组件名称（小写），配置后只通知这些组件及影响它们的事件
+	IncludeComponentSummary      bool              // 是否在变更通知末尾附加非正常组件数量
+	MonitorOverallStatus         bool              // 是否监控状态页整体状态指示
+	MonitorUptimeSLA             bool              // 是否监控组件的 90 天滚动可用率
+	UptimeSLAThreshold           float64           // 可用率告警阈值（百分比）
+	StatusBoard                  bool              // 是否维护一条汇总进行中事件的状态面板消息
+	StatusBoardRepostMinutes     int               // 不支持编辑的渠道重新发送状态面板的最短间隔（分钟）
+	TemplateFile                 string            // 自定义通知模板文件路径
+	NewTitleTemplate             string            // 只包含新事件的变更通知标题模板
+	UpdateTitleTemplate          string            // 其余变更通知的标题模板
+	NotificationFooter           string            // 通知页脚模板（Markdown），可使用 .StatusPageURL 和 .StatusPageURLs
+	FeishuWebhook                string
+	FeishuSecret                 string
+	WechatWorkWebhookKey         string         // 企业微信群机器人 Webhook 的 key
+	NtfyURL                      string         // ntfy 主题地址
+	NtfyToken                    string         // ntfy 访问令牌，为空时不认证
+	DingtalkRateLimit            int            // 钉钉每分钟最多发送的消息数
+	NotifyRateLimit              int            // 所有渠道合计每分钟最多发送的通知数，0 表示不限制
+	CBFailureThreshold           int            // 钉钉连续发送失败多少次后打开熔断器，0 表示不启用
+	CBCooldownSeconds            int            // 熔断器打开后的冷却时间（秒）
+	HealthListenAddr             string         // 健康检查和查询接口的监听地址，为空时不启动
+	DedupAcrossPages             bool           // 是否对多个状态页中的相同事件去重
+	DedupWindowMinutes           int            // 去重时允许的创建时间差
+	CorrelateIncidents           bool           // 是否在通知和每日报告中将名称相似或时间重叠的相关事件归为一组
+	ThreadUpdates                bool           // 是否将事件的后续更新关联到首次通知（支持回复的渠道以回复形式发送）
+	RegionKeywords               []string       // 地区关键词，配置后只通知匹配的事件
+	IncidentNameAllowRegex       *regexp.Regexp // 事件名称白名单正则，配置后只通知匹配的事件
+	IncidentNameBlockRegex       *regexp.Regexp // 事件名称黑名单正则，匹配的事件不通知，优先于白名单
+	DBPath                       string         // SQLite 事件历史数据库路径
+	CatchupOnStartup             bool           // 启动时是否汇总通知监控离线期间的事件，需要 DB_PATH
+	ColorizeOutput               bool           // 是否在事件标题前添加彩色影响程度标记和状态图标
+	ShowFullIncidentID           bool           // 事件详情中是否展示完整的事件 ID，关闭时只展示前 8 个字符
+	TimeFormat                   string         // 通知中时间的 Go 格式布局
+	CompactNotifications         bool           // 实时变更通知是否使用每个事件一行的紧凑格式
+	ShowUpdateDiffs              bool           // 更新内容被修改时是否在通知中展示差异
+	NotificationMode             string         // 变更通知模式: batched 或 individual
+	ObserveOnly                  bool           // 只采集数据不发送任何通知
+	CheckTriggerToken            string         // POST /check 接口的 Bearer 令牌，为空时不允许手动触发
+	ApprovalMode                 bool           // 变更通知是否需要人工批准后才发送
+	ApprovalNotifier             string         // 发送待批准提醒的通知渠道，必须是 NOTIFIERS 中的渠道
+	ApprovalTimeoutMinutes       int            // 待批准通知的有效期
+	ApprovalExpireAction         string         // 待批准通知过期后的处理方式: send 或 drop
+	ReportIncludeStats           bool           // 每日报告是否包含统计摘要和状态停留时长
+	ReportIncludeHistory         bool           // 每日报告是否包含事件的完整更新历史
+	ReportIncludeMaintenances    bool           // 每日报告是否包含即将进行的计划维护
+	ReportSortOrder              string         // 每日报告事件排序: time 或 impact
+	ReportGroupByImpact          bool           // 每日报告是否按影响程度分组列出事件
+	ReportMaxIncidents           int            // 每日报告最多列出的事件数，0 表示不限制
+	ReportDedupWindowMinutes     int            // 每日报告中该时间内已单独通知过的事件不再重复列出，0 表示不启用
+	ReportDedupMode              string         // 已通知事件的处理方式: omit 或 mark
+	SendEmptyDailyReport         bool           // 没有事件时是否仍发送"系统正常"的每日报告
+	GenerateTimelines            bool           // 事件解决时是否生成完整时间线 HTML 页面
+	TimelineDir                  string         // 时间线页面的输出目录
+	TimelineBaseURL              string         // 对外提供时间线目录的 URL 前缀，为空时通知中给出文件路径
 }
 
 // Incident 结构体用于解析单个事件数据
 type Incident struct {
-	ID              string    `json:"id"`
-	Name            string    `json:"name"`
-	Status          string    `json:"status"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	MonitoringAt    time.Time `json:"monitoring_at"`
-	ResolvedAt      time.Time `json:"resolved_at"`
-	Impact          string    `json:"impact"`
-	Shortlink       string    `json:"shortlink"`
-	IncidentUpdates []Update  `json:"incident_updates"`
+	ID              string      `json:"id"`
+	Name            string      `json:"name"`
+	Status          string      `json:"status"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+	MonitoringAt    time.Time   `json:"monitoring_at"`
+	ResolvedAt      time.Time   `json:"resolved_at"`
+	Impact          string      `json:"impact"`
+	Shortlink       string      `json:"shortlink"`
+	IncidentUpdates []Update    `json:"incident_updates"`
+	Components      []Component `json:"components,omitempty"` // 受影响的组件
+	Page            string      `json:"page,omitempty"`       // 事件所属的状态页地址
+}
+
+// 事件是否已解决
+func (i Incident) isResolved() bool {
+	return !i.ResolvedAt.IsZero() || i.Status == "resolved" || i.Status == "postmortem"
+}
+
+// 事件是否处于进行中的状态（investigating、identified 或 monitoring）
+func (i Incident) isActiveStatus() bool {
+	switch i.Status {
+	case "investigating", "identified", "monitoring":
+		return true
+	}
+	return false
+}
+
+// 获取事件的解决时间，resolved_at 缺失时依次回退到最近一条 resolved 更新和 UpdatedAt
+func (i Incident) resolvedTime() time.Time {
+	if !i.ResolvedAt.IsZero() {
+		return i.ResolvedAt
+	}
+	if !i.isResolved() {
+		return time.Time{}
+	}
+
+	var latest time.Time
+	for _, update := range i.IncidentUpdates {
+		if update.Status == "resolved" && update.CreatedAt.After(latest) {
+			latest = update.CreatedAt
+		}
+	}
+	if !latest.IsZero() {
+		return latest
+	}
+	return i.UpdatedAt
+}
+
+// 计算事件从创建到解决的时长，未解决或时钟偏差导致负值时返回 false
+func (i Incident) resolutionDuration() (time.Duration, bool) {
+	resolvedAt := i.resolvedTime()
+	if resolvedAt.IsZero() || i.CreatedAt.IsZero() {
+		return 0, false
+	}
+	duration := resolvedAt.Sub(i.CreatedAt)
+	if duration < 0 {
+		return 0, true
+	}
+	return duration, true
+}
+
+// 获取最近的一条更新记录
+func (i Incident) latestUpdate() (Update, bool) {
+	if len(i.IncidentUpdates) == 0 {
+		return Update{}, false
+	}
+	latest := i.IncidentUpdates[0]
+	for _, update := range i.IncidentUpdates[1:] {
+		if update.CreatedAt.After(latest.CreatedAt) {
+			latest = update
+		}
+	}
+	return latest, true
+}
+
+// 与缓存的事件相比是否新增了更新记录：数量增加或最新一条更新的 ID 不同。
+// 部分状态页镜像追加更新时不会修改顶层的 updated_at，需要单独比较
+func (i Incident) hasNewUpdate(cached Incident) bool {
+	if len(i.IncidentUpdates) > len(cached.IncidentUpdates) {
+		return true
+	}
+	latest, ok := i.latestUpdate()
+	if !ok {
+		return false
+	}
+	cachedLatest, cachedOK := cached.latestUpdate()
+	return !cachedOK || latest.ID != cachedLatest.ID
+}
+
+// 影响程度排序，数值越大越严重
+var impactRank = map[string]int{
+	"none":     0,
+	"minor":    1,
+	"major":    2,
+	"critical": 3,
 }
 
 // Update 结构体用于解析事件更新数据
@@ -59,14 +270,84 @@ type Response struct {
 
 // Service 服务结构体
 type Service struct {
-	config         Config
+	// 当前时间的来源，默认为 time.Now；调度、回溯窗口和通知中的时间戳都通过它获取，便于测试时注入固定时钟
+	Now func() time.Time
+
+	// 当前生效的配置和通知渠道，通过 snapshot/config 读取，重新加载时整体替换
+	current atomic.Pointer[runtimeConfig]
+
 	lastIncidents  map[string]Incident
 	mutex          sync.RWMutex
-	lastCheckTime  time.Time
-	lastReportTime time.Time
-	statusVersion  string // 添加版本信息字段
+	lastCheckTime  time.Time // 最近一次成功完成检查的时间，由 mutex 保护
+	lastReportTime time.Time // 最近一次发送每日报告的时间，由 mutex 保护
+	lastHeartbeat  time.Time // 主循环最近一次触发的时间
+	statusVersion  string    // 添加版本信息字段
+
+	deferredChanges []incidentChange // 静默时段内延迟发送的变化
+
+	consecutiveFailures int  // 连续获取失败次数
+	degradedAlertSent   bool // 是否已发送降级告警
+
+	componentStatus map[string]Component // 组件状态缓存，键为 状态页|组件ID
+	overallStatus   map[string]Status    // 各状态页上次获取的整体状态
+
+	notifyQueue *deliveryQueue // 钉钉发送失败的消息持久化队列，首次创建钉钉渠道时打开，重新加载时复用
+	sentKeys    *sentKeyStore  // 钉钉最近已发送变更通知的去重键，首次创建钉钉渠道时打开，重新加载时复用
+
+	notifiedNames map[string][]notifiedIncident // 已通知事件的名称指纹，用于跨状态页去重
+	duplicateOf   map[string]string             // 被判定为重复的事件 ID -> 原始事件 ID
+
+	history *historyStore // SQLite 事件历史存储，未配置 DB_PATH 时为 nil
+
+	checkMutex sync.Mutex // 串行化定时检查和手动触发的检查
+
+	mutedUntil map[string]time.Time // 通过 /mute 接口静音的事件 ID -> 静音截止时间
+
+	pageCache map[string]pageCacheEntry // 各状态页上次响应的 ETag/Last-Modified 和事件
+
+	componentCache     map[string]componentCacheEntry // 各状态页 components.json 上次响应的缓存验证信息和组件
+	componentGroups    map[string]string              // 分组组件名称，键为 状态页|分组ID
+	degradedComponents int                            // 最近一次获取到的非正常组件数量，-1 表示未知
+
+	longIncidentAlerted map[string]bool // 已发送长时间未解决提醒的事件 ID
+
+	uptimeBreached  map[string]bool // 已发送可用率告警的组件，键为 状态页|组件ID
+	lastUptimeCheck time.Time       // 上次检查组件可用率的时间
+
+	warmupRemaining int // 冷启动时剩余的 WARMUP_CHECKS 预热检查次数，由 checkMutex 保护
+
+	boardMessageIDs map[string]string    // 支持编辑的通知渠道名称 -> 状态面板消息 ID
+	boardBodies     map[string]string    // 各通知渠道最近一次发送的状态面板事件列表
+	boardPostedAt   map[string]time.Time // 各通知渠道最近一次发送状态面板的时间
+
+	pollInterval          time.Duration // 当前生效的检查间隔，为零时使用 CHECK_INTERVAL_MINUTES
+	pollIntervalChangedAt time.Time     // 上次切换检查间隔的时间
+
+	dispatcher   *notificationDispatcher // 外发通知的发送队列，首次发送时启动
+	dispatchOnce sync.Once
+
+	threads *threadStore // 各事件首次通知的记录，用于 THREAD_UPDATES
+
+	approvals *approvalQueue // APPROVAL_MODE 下等待人工批准的变更通知
+
+	configPath string        // 启动时使用的配置文件路径，POST /reload 时重新读取
+	reloaded   chan struct{} // 通过 POST /reload 重新加载配置后通知主循环重置定时器
+}
+
+// incidentChange 一次检测到的事件变化及其通知正文
+type incidentChange struct {
+	Section string
+	Event   IncidentEvent
+	// 影响程度升级或事件重新开启的变化不受 MIN_IMPACT_LEVEL 过滤，并在钉钉中 @所有人
+	Escalated bool
 }
 
+// 钉钉机器人安全设置，需与机器人后台的配置一致
+const (
+	dingtalkSecuritySign    = "sign"    // 加签
+	dingtalkSecurityKeyword = "keyword" // 自定义关键词
+)
+
 // 钉钉消息结构体
 type DingtalkMessage struct {
 	Msgtype  string `json:"msgtype"`
@@ -74,19 +355,77 @@ type DingtalkMessage struct {
 		Title string `json:"title"`
 		Text  string `json:"text"`
 	} `json:"markdown"`
+	At struct {
+		IsAtAll bool `json:"isAtAll"`
+	} `json:"at"`
 }
 
 // 加载配置文件
 func loadConfig(configPath string) (Config, error) {
-	var config Config
-
-	file, err := os.Open(configPath)
+	file, err := openConfigSource(configPath)
 	if err != nil {
-		return config, fmt.Errorf("打开配置文件失败: %v", err)
+		return Config{}, err
 	}
 	defer file.Close()
+	return parseConfig(file)
+}
+
+// 解析 KEY=value 格式的配置并校验，未出现的配置项使用默认值
+func parseConfig(r io.Reader) (Config, error) {
+	config := Config{
+		LogFormat:                    logFormatText,
+		LogLevel:                     logLevelInfo,
+		Notifiers:                    []string{"dingtalk"},
+		SendStartupNotification:      true,
+		NotifyOldActiveIncidents:     true,
+		ShowFullIncidentID:           true,
+		TimeFormat:                   defaultTimeFormat,
+		ApprovalTimeoutMinutes:       30,
+		ApprovalExpireAction:         approvalExpireSend,
+		StatusPageURL:                "https://www.cloudflarestatus.com",
+		ProbeStatusPages:             true,
+		UptimeSLAThreshold:           99.9,
+		StatusBoardRepostMinutes:     60,
+		FetchConcurrency:             4,
+		NotifyRetryCount:             3,
+		QuietHoursStart:              -1,
+		QuietHoursEnd:                -1,
+		DingtalkRateLimit:            20,
+		CBFailureThreshold:           5,
+		CBCooldownSeconds:            300,
+		DedupWindowMinutes:           30,
+		CacheRetentionDays:           7,
+		UpdateDisplayMode:            updateDisplayLatest,
+		ShowUpdateHistory:            true,
+		MaxUpdatesInDetail:           5,
+		NotificationMode:             notificationModeBatched,
+		DingtalkSecurityMode:         dingtalkSecuritySign,
+		DingtalkBaseURL:              defaultDingtalkBaseURL,
+		DingtalkAuthFailureThreshold: 3,
+		MinImpactLevel:               "none",
+		DuplicateIncidentPolicy:      duplicatePolicyLatest,
+		SLAImpactLevels:              []string{"major", "critical"},
+		UserAgent:                    "Get-Cf-status/1.0",
+		ReportIncludeStats:           true,
+		ReportIncludeHistory:         true,
+		ReportSortOrder:              reportSortTime,
+		ReportDedupMode:              reportDedupOmit,
+		SendEmptyDailyReport:         true,
+		NewTitleTemplate:             defaultTitleTemplate,
+		UpdateTitleTemplate:          defaultTitleTemplate,
+		NotificationFooter:           defaultFooterTemplate,
+		MaxConsecutiveFailures:       3,
+		NotifyQueueMaxAgeHours:       24,
+		NotifyDedupTTLMinutes:        60,
+		DebugDumpKeep:                20,
+		MaxResponseBytes:             10 * 1024 * 1024,
+	}
+
+	// 从文件读取的密钥路径，适配 Docker/Kubernetes secrets
+	var tokenFile, secretFile string
 
-	scanner := bufio.NewScanner(file)
+	var err error
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
@@ -108,10 +447,52 @@ func loadConfig(configPath string) (Config, error) {
 			if interval, err := strconv.Atoi(value); err == nil {
 				config.CheckIntervalMinutes = interval
 			}
+		case "ACTIVE_CHECK_INTERVAL_MINUTES":
+			if interval, err := strconv.Atoi(value); err == nil {
+				config.ActiveCheckIntervalMinutes = interval
+			}
+		case "POLL_JITTER_SECONDS":
+			if seconds, err := strconv.Atoi(value); err == nil {
+				config.PollJitterSeconds = seconds
+			}
+		case "DEBUG_DUMP_DIR":
+			config.DebugDumpDir = value
+		case "DEBUG_DUMP_KEEP":
+			if keep, err := strconv.Atoi(value); err == nil {
+				config.DebugDumpKeep = keep
+			}
+		case "MAX_RESPONSE_BYTES":
+			if limit, err := strconv.ParseInt(value, 10, 64); err == nil {
+				config.MaxResponseBytes = limit
+			}
+		case "NOTIFY_QUEUE_FILE":
+			config.NotifyQueueFile = value
+		case "NOTIFY_QUEUE_MAX_AGE_HOURS":
+			if hours, err := strconv.Atoi(value); err == nil {
+				config.NotifyQueueMaxAgeHours = hours
+			}
+		case "NOTIFY_DEDUP_FILE":
+			config.NotifyDedupFile = value
+		case "NOTIFY_DEDUP_TTL_MINUTES":
+			if minutes, err := strconv.Atoi(value); err == nil {
+				config.NotifyDedupTTLMinutes = minutes
+			}
 		case "DAILY_REPORT_UTC_HOUR":
 			if hour, err := strconv.Atoi(value); err == nil {
 				config.DailyReportUTCHour = hour
 			}
+		case "DAILY_REPORT_HOURS":
+			config.DailyReportHours = nil
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				hour, err := strconv.Atoi(item)
+				if err != nil {
+					return config, fmt.Errorf("DAILY_REPORT_HOURS 包含无效的小时: %s", item)
+				}
+				config.DailyReportHours = append(config.DailyReportHours, hour)
+			}
 		case "MAX_INCIDENTS":
 			if max, err := strconv.Atoi(value); err == nil {
 				config.MaxIncidents = max
@@ -120,6 +501,364 @@ func loadConfig(configPath string) (Config, error) {
 			config.DingtalkWebhookToken = value
 		case "DINGTALK_SECRET":
 			config.DingtalkSecret = value
+		case "DINGTALK_CRITICAL_WEBHOOK":
+			config.DingtalkCriticalWebhook = value
+		case "DINGTALK_CRITICAL_SECRET":
+			config.DingtalkCriticalSecret = value
+		case "DINGTALK_INFO_WEBHOOK":
+			config.DingtalkInfoWebhook = value
+		case "DINGTALK_INFO_SECRET":
+			config.DingtalkInfoSecret = value
+		case "DINGTALK_ROUTES":
+			routes, err := parseDingtalkRoutes(value)
+			if err != nil {
+				return config, err
+			}
+			config.DingtalkRoutes = routes
+		case "DINGTALK_AUTH_FAILURE_THRESHOLD":
+			if count, err := strconv.Atoi(value); err == nil {
+				config.DingtalkAuthFailureThreshold = count
+			}
+		case "DINGTALK_FALLBACK_NOTIFIER":
+			config.DingtalkFallbackNotifier = strings.ToLower(value)
+		case "DINGTALK_SECURITY_MODE":
+			config.DingtalkSecurityMode = strings.ToLower(value)
+		case "DINGTALK_KEYWORD":
+			config.DingtalkKeyword = value
+		case "DINGTALK_BASE_URL":
+			config.DingtalkBaseURL = strings.TrimRight(value, "/")
+		case "DINGTALK_WEBHOOK_TOKEN_FILE":
+			tokenFile = value
+		case "DINGTALK_SECRET_FILE":
+			secretFile = value
+		case "LOG_FORMAT":
+			config.LogFormat = strings.ToLower(value)
+		case "LOG_LEVEL":
+			config.LogLevel = strings.ToLower(value)
+		case "LOG_FILE":
+			config.LogFile = value
+		case "LOG_MAX_SIZE_MB":
+			if size, err := strconv.Atoi(value); err == nil {
+				config.LogMaxSizeMB = size
+			}
+		case "NOTIFIERS":
+			config.Notifiers = splitList(value)
+		case "WEBHOOK_URL":
+			config.WebhookURL = value
+		case "WEBHOOK_TOKEN":
+			config.WebhookToken = value
+		case "WEBHOOK_SIGNING_SECRET":
+			config.WebhookSigningSecret = value
+		case "STATUS_PAGE_URL":
+			config.StatusPageURL = normalizeStatusPageURL(value)
+		case "STATUS_PAGE_LOCALES":
+			locales, err := parsePageLocales(value)
+			if err != nil {
+				return config, err
+			}
+			config.PageLocales = locales
+		case "STATUS_PAGE_LOCALE_QUERY_PARAM":
+			config.PageLocaleQueryParam = value
+		case "STATUS_PAGES":
+			config.StatusPages = nil
+			config.PageNames = make(map[string]string)
+			for _, entry := range strings.Split(value, ",") {
+				name, page := parseStatusPageEntry(entry)
+				if page == "" {
+					continue
+				}
+				config.StatusPages = append(config.StatusPages, page)
+				if name != "" {
+					config.PageNames[page] = name
+				}
+			}
+		case "PROBE_STATUS_PAGES":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ProbeStatusPages = enabled
+			}
+		case "FETCH_CONCURRENCY":
+			if concurrency, err := strconv.Atoi(value); err == nil {
+				config.FetchConcurrency = concurrency
+			}
+		case "NOTIFY_RETRY_COUNT":
+			if count, err := strconv.Atoi(value); err == nil {
+				config.NotifyRetryCount = count
+			}
+		case "QUIET_HOURS_START":
+			if hour, err := strconv.Atoi(value); err == nil {
+				config.QuietHoursStart = hour
+			}
+		case "QUIET_HOURS_END":
+			if hour, err := strconv.Atoi(value); err == nil {
+				config.QuietHoursEnd = hour
+			}
+		case "CACHE_RETENTION_DAYS":
+			if days, err := strconv.Atoi(value); err == nil {
+				config.CacheRetentionDays = days
+			}
+		case "STATE_FILE":
+			config.StateFile = value
+		case "UPDATE_DISPLAY_MODE":
+			config.UpdateDisplayMode = strings.ToLower(value)
+		case "SHOW_UPDATE_HISTORY":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ShowUpdateHistory = enabled
+			}
+		case "MAX_UPDATES_IN_DETAIL":
+			if count, err := strconv.Atoi(value); err == nil {
+				config.MaxUpdatesInDetail = count
+			}
+		case "MAX_CONSECUTIVE_FAILURES":
+			if count, err := strconv.Atoi(value); err == nil {
+				config.MaxConsecutiveFailures = count
+			}
+		case "MONITOR_COMPONENTS":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.MonitorComponents = enabled
+			}
+		case "WATCH_COMPONENTS":
+			config.WatchComponents = nil
+			for _, name := range strings.Split(value, ";") {
+				if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+					config.WatchComponents = append(config.WatchComponents, name)
+				}
+			}
+		case "INCLUDE_COMPONENT_SUMMARY":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.IncludeComponentSummary = enabled
+			}
+		case "MONITOR_OVERALL_STATUS":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.MonitorOverallStatus = enabled
+			}
+		case "MONITOR_UPTIME_SLA":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.MonitorUptimeSLA = enabled
+			}
+		case "UPTIME_SLA_THRESHOLD":
+			if threshold, err := strconv.ParseFloat(value, 64); err == nil {
+				config.UptimeSLAThreshold = threshold
+			}
+		case "STATUS_BOARD":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.StatusBoard = enabled
+			}
+		case "STATUS_BOARD_REPOST_MINUTES":
+			if minutes, err := strconv.Atoi(value); err == nil {
+				config.StatusBoardRepostMinutes = minutes
+			}
+		case "TEMPLATE_FILE":
+			config.TemplateFile = value
+		case "FEISHU_WEBHOOK":
+			config.FeishuWebhook = value
+		case "FEISHU_SECRET":
+			config.FeishuSecret = value
+		case "WECHAT_WORK_WEBHOOK_KEY":
+			config.WechatWorkWebhookKey = value
+		case "NTFY_URL":
+			config.NtfyURL = strings.TrimRight(value, "/")
+		case "NTFY_TOKEN":
+			config.NtfyToken = value
+		case "NOTIFY_RATE_LIMIT_PER_MINUTE":
+			if limit, err := strconv.Atoi(value); err == nil {
+				config.NotifyRateLimit = limit
+			}
+		case "DINGTALK_RATE_LIMIT_PER_MINUTE":
+			if limit, err := strconv.Atoi(value); err == nil {
+				config.DingtalkRateLimit = limit
+			}
+		case "CB_FAILURE_THRESHOLD":
+			if threshold, err := strconv.Atoi(value); err == nil {
+				config.CBFailureThreshold = threshold
+			}
+		case "CB_COOLDOWN_SECONDS":
+			if seconds, err := strconv.Atoi(value); err == nil {
+				config.CBCooldownSeconds = seconds
+			}
+		case "HEALTH_LISTEN_ADDR":
+			config.HealthListenAddr = value
+		case "DEDUP_ACROSS_PAGES":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.DedupAcrossPages = enabled
+			}
+		case "THREAD_UPDATES":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ThreadUpdates = enabled
+			}
+		case "CORRELATE_INCIDENTS":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.CorrelateIncidents = enabled
+			}
+		case "DEDUP_WINDOW_MINUTES":
+			if minutes, err := strconv.Atoi(value); err == nil {
+				config.DedupWindowMinutes = minutes
+			}
+		case "USER_AGENT":
+			config.UserAgent = value
+		case "REQUEST_HEADERS":
+			headers, err := parseRequestHeaders(value)
+			if err != nil {
+				return config, err
+			}
+			config.RequestHeaders = headers
+		case "POST_RESOLUTION_QUIET_MINUTES":
+			if minutes, err := strconv.Atoi(value); err == nil {
+				config.PostResolutionQuietMinutes = minutes
+			}
+		case "LONG_INCIDENT_THRESHOLD_MINUTES":
+			if minutes, err := strconv.Atoi(value); err == nil {
+				config.LongIncidentThresholdMinutes = minutes
+			}
+		case "SLA_IMPACT_LEVELS":
+			config.SLAImpactLevels = splitList(value)
+		case "MIN_IMPACT_LEVEL":
+			config.MinImpactLevel = strings.ToLower(value)
+		case "NOTIFY_STATUSES":
+			config.NotifyStatuses = splitList(value)
+		case "IMPACT_LABELS":
+			labels, err := parseImpactLabels(value)
+			if err != nil {
+				return config, err
+			}
+			config.ImpactLabels = labels
+		case "DUPLICATE_INCIDENT_POLICY":
+			config.DuplicateIncidentPolicy = strings.ToLower(value)
+		case "CONFIRM_CRITICAL_ALERTS":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ConfirmCriticalAlerts = enabled
+			}
+		case "NOTIFY_ALL_NEW_INCIDENTS":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.NotifyAllNewIncidents = enabled
+			}
+		case "REGION_KEYWORDS":
+			config.RegionKeywords = splitList(value)
+		case "INCIDENT_NAME_ALLOW_REGEX", "INCIDENT_NAME_BLOCK_REGEX":
+			pattern, err := regexp.Compile(value)
+			if err != nil {
+				return config, fmt.Errorf("%s 不是有效的正则表达式: %v", key, err)
+			}
+			if key == "INCIDENT_NAME_ALLOW_REGEX" {
+				config.IncidentNameAllowRegex = pattern
+			} else {
+				config.IncidentNameBlockRegex = pattern
+			}
+		case "DB_PATH":
+			config.DBPath = value
+		case "CATCHUP_ON_STARTUP":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.CatchupOnStartup = enabled
+			}
+		case "REPORT_INCLUDE_STATS":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ReportIncludeStats = enabled
+			}
+		case "REPORT_INCLUDE_UPDATE_HISTORY":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ReportIncludeHistory = enabled
+			}
+		case "GENERATE_TIMELINES":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.GenerateTimelines = enabled
+			}
+		case "TIMELINE_DIR":
+			config.TimelineDir = value
+		case "TIMELINE_BASE_URL":
+			config.TimelineBaseURL = value
+		case "NOTIFICATION_FOOTER":
+			// 配置文件中每项只占一行，页脚中的换行写作 \n
+			footer := strings.ReplaceAll(value, `\n`, "\n")
+			if _, err := parseFooterTemplate(footer); err != nil {
+				return config, fmt.Errorf("NOTIFICATION_FOOTER 无效: %v", err)
+			}
+			config.NotificationFooter = footer
+		case "NEW_TITLE_TEMPLATE", "UPDATE_TITLE_TEMPLATE":
+			if _, err := parseTitleTemplate(key, value); err != nil {
+				return config, fmt.Errorf("%s 无效: %v", key, err)
+			}
+			if key == "NEW_TITLE_TEMPLATE" {
+				config.NewTitleTemplate = value
+			} else {
+				config.UpdateTitleTemplate = value
+			}
+		case "REPORT_GROUP_BY_IMPACT":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ReportGroupByImpact = enabled
+			}
+		case "SEND_EMPTY_DAILY_REPORT":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.SendEmptyDailyReport = enabled
+			}
+		case "REPORT_SORT_ORDER":
+			config.ReportSortOrder = strings.ToLower(value)
+		case "REPORT_MAX_INCIDENTS":
+			if max, err := strconv.Atoi(value); err == nil {
+				config.ReportMaxIncidents = max
+			}
+		case "REPORT_DEDUP_WINDOW_MINUTES":
+			if minutes, err := strconv.Atoi(value); err == nil {
+				config.ReportDedupWindowMinutes = minutes
+			}
+		case "REPORT_DEDUP_MODE":
+			config.ReportDedupMode = strings.ToLower(value)
+		case "REPORT_INCLUDE_MAINTENANCES":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ReportIncludeMaintenances = enabled
+			}
+		case "CHECK_TRIGGER_TOKEN":
+			config.CheckTriggerToken = value
+		case "APPROVAL_MODE":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ApprovalMode = enabled
+			}
+		case "APPROVAL_NOTIFIER":
+			config.ApprovalNotifier = strings.ToLower(value)
+		case "APPROVAL_TIMEOUT_MINUTES":
+			if minutes, err := strconv.Atoi(value); err == nil {
+				config.ApprovalTimeoutMinutes = minutes
+			}
+		case "APPROVAL_EXPIRE_ACTION":
+			config.ApprovalExpireAction = strings.ToLower(value)
+		case "OBSERVE_ONLY":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ObserveOnly = enabled
+			}
+		case "NOTIFICATION_MODE":
+			config.NotificationMode = strings.ToLower(value)
+		case "SHOW_UPDATE_DIFFS":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ShowUpdateDiffs = enabled
+			}
+		case "COMPACT_NOTIFICATIONS":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.CompactNotifications = enabled
+			}
+		case "TIME_FORMAT":
+			config.TimeFormat = value
+		case "SHOW_FULL_INCIDENT_ID":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ShowFullIncidentID = enabled
+			}
+		case "COLORIZE_OUTPUT":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.ColorizeOutput = enabled
+			}
+		case "SEND_STARTUP_NOTIFICATION":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.SendStartupNotification = enabled
+			}
+		case "STARTUP_INCLUDE_RESOLVED":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.StartupIncludeResolved = enabled
+			}
+		case "WARMUP_CHECKS":
+			if checks, err := strconv.Atoi(value); err == nil {
+				config.WarmupChecks = checks
+			}
+		case "NOTIFY_OLD_ACTIVE_INCIDENTS":
+			if enabled, err := strconv.ParseBool(value); err == nil {
+				config.NotifyOldActiveIncidents = enabled
+			}
 		}
 	}
 
@@ -127,401 +866,2690 @@ func loadConfig(configPath string) (Config, error) {
 		return config, fmt.Errorf("读取配置文件失败: %v", err)
 	}
 
+	if config.DingtalkWebhookToken, err = resolveSecret("DINGTALK_WEBHOOK_TOKEN", config.DingtalkWebhookToken, tokenFile); err != nil {
+		return config, err
+	}
+	if config.DingtalkSecret, err = resolveSecret("DINGTALK_SECRET", config.DingtalkSecret, secretFile); err != nil {
+		return config, err
+	}
+	for _, token := range []struct {
+		name  string
+		value *string
+	}{
+		{"DINGTALK_WEBHOOK_TOKEN", &config.DingtalkWebhookToken},
+		{"DINGTALK_CRITICAL_WEBHOOK", &config.DingtalkCriticalWebhook},
+		{"DINGTALK_INFO_WEBHOOK", &config.DingtalkInfoWebhook},
+	} {
+		if *token.value, err = normalizeDingtalkToken(token.name, *token.value); err != nil {
+			return config, err
+		}
+	}
+
 	// 验证必要的配置项
 	if config.CheckIntervalMinutes <= 0 {
 		return config, fmt.Errorf("CHECK_INTERVAL_MINUTES 必须大于0")
 	}
-	if config.DailyReportUTCHour < 0 || config.DailyReportUTCHour > 23 {
-		return config, fmt.Errorf("DAILY_REPORT_UTC_HOUR 必须在0-23之间")
+	if config.ActiveCheckIntervalMinutes < 0 || config.ActiveCheckIntervalMinutes > config.CheckIntervalMinutes {
+		return config, fmt.Errorf("ACTIVE_CHECK_INTERVAL_MINUTES 必须在0到 CHECK_INTERVAL_MINUTES 之间")
+	}
+	if config.WarmupChecks < 0 {
+		return config, fmt.Errorf("WARMUP_CHECKS 不能小于0")
+	}
+	if config.PollJitterSeconds < 0 {
+		return config, fmt.Errorf("POLL_JITTER_SECONDS 不能小于0")
+	}
+	if config.DailyReportUTCHour < 0 || config.DailyReportUTCHour > 23 {
+		return config, fmt.Errorf("DAILY_REPORT_UTC_HOUR 必须在0-23之间")
+	}
+	if len(config.DailyReportHours) == 0 {
+		config.DailyReportHours = []int{config.DailyReportUTCHour}
+	}
+	for _, hour := range config.DailyReportHours {
+		if hour < 0 || hour > 23 {
+			return config, fmt.Errorf("DAILY_REPORT_HOURS 中的小时必须在0-23之间: %d", hour)
+		}
+	}
+	sort.Ints(config.DailyReportHours)
+	if config.MaxIncidents <= 0 {
+		return config, fmt.Errorf("MAX_INCIDENTS 必须大于0")
+	}
+	if len(config.Notifiers) == 0 {
+		return config, fmt.Errorf("NOTIFIERS 至少需要配置一个通知渠道")
+	}
+	if config.DebugDumpKeep <= 0 {
+		return config, fmt.Errorf("DEBUG_DUMP_KEEP 必须大于0")
+	}
+	if config.MaxResponseBytes <= 0 {
+		return config, fmt.Errorf("MAX_RESPONSE_BYTES 必须大于0")
+	}
+	if config.NotifyQueueMaxAgeHours <= 0 {
+		return config, fmt.Errorf("NOTIFY_QUEUE_MAX_AGE_HOURS 必须大于0")
+	}
+	if config.NotifyDedupTTLMinutes <= 0 {
+		return config, fmt.Errorf("NOTIFY_DEDUP_TTL_MINUTES 必须大于0")
+	}
+	if config.CatchupOnStartup && config.DBPath == "" {
+		return config, fmt.Errorf("启用 CATCHUP_ON_STARTUP 时 DB_PATH 不能为空")
+	}
+	if config.GenerateTimelines && config.TimelineDir == "" {
+		return config, fmt.Errorf("启用 GENERATE_TIMELINES 时 TIMELINE_DIR 不能为空")
+	}
+	channels := config.Notifiers
+	if config.DingtalkFallbackNotifier != "" {
+		if config.DingtalkFallbackNotifier == "dingtalk" {
+			return config, fmt.Errorf("DINGTALK_FALLBACK_NOTIFIER 不能是 dingtalk")
+		}
+		for _, name := range config.Notifiers {
+			if name == config.DingtalkFallbackNotifier {
+				return config, fmt.Errorf("DINGTALK_FALLBACK_NOTIFIER 不能是已在 NOTIFIERS 中启用的渠道: %s", name)
+			}
+		}
+		channels = append(append([]string{}, config.Notifiers...), config.DingtalkFallbackNotifier)
+	}
+	if config.ObserveOnly {
+		// 只采集数据时不创建通知渠道，无需校验渠道凭证
+		channels = nil
+	}
+	for _, name := range channels {
+		switch name {
+		case "dingtalk":
+			if config.DingtalkWebhookToken == "" {
+				return config, fmt.Errorf("DINGTALK_WEBHOOK_TOKEN 不能为空")
+			}
+			switch config.DingtalkSecurityMode {
+			case dingtalkSecuritySign:
+				if config.DingtalkSecret == "" {
+					return config, fmt.Errorf("DINGTALK_SECRET 不能为空")
+				}
+				if config.DingtalkCriticalWebhook != "" && config.DingtalkCriticalSecret == "" {
+					return config, fmt.Errorf("配置 DINGTALK_CRITICAL_WEBHOOK 时 DINGTALK_CRITICAL_SECRET 不能为空")
+				}
+				if config.DingtalkInfoWebhook != "" && config.DingtalkInfoSecret == "" {
+					return config, fmt.Errorf("配置 DINGTALK_INFO_WEBHOOK 时 DINGTALK_INFO_SECRET 不能为空")
+				}
+				for _, route := range config.DingtalkRoutes {
+					if route.Secret == "" {
+						return config, fmt.Errorf("DINGTALK_ROUTES 中关键词 %s 缺少 secret", route.Keyword)
+					}
+				}
+			case dingtalkSecurityKeyword:
+				if config.DingtalkKeyword == "" {
+					return config, fmt.Errorf("DINGTALK_SECURITY_MODE 为 keyword 时 DINGTALK_KEYWORD 不能为空")
+				}
+			default:
+				return config, fmt.Errorf("DINGTALK_SECURITY_MODE 必须是 sign 或 keyword")
+			}
+			if config.DingtalkRateLimit <= 0 {
+				return config, fmt.Errorf("DINGTALK_RATE_LIMIT_PER_MINUTE 必须大于0")
+			}
+			if config.CBFailureThreshold < 0 {
+				return config, fmt.Errorf("CB_FAILURE_THRESHOLD 不能小于0")
+			}
+			if config.CBCooldownSeconds <= 0 {
+				return config, fmt.Errorf("CB_COOLDOWN_SECONDS 必须大于0")
+			}
+			if config.DingtalkAuthFailureThreshold <= 0 {
+				return config, fmt.Errorf("DINGTALK_AUTH_FAILURE_THRESHOLD 必须大于0")
+			}
+		case "webhook":
+			if config.WebhookURL == "" {
+				return config, fmt.Errorf("启用 webhook 通知时 WEBHOOK_URL 不能为空")
+			}
+		case "feishu":
+			if config.FeishuWebhook == "" {
+				return config, fmt.Errorf("启用 feishu 通知时 FEISHU_WEBHOOK 不能为空")
+			}
+		case "wechat_work":
+			if config.WechatWorkWebhookKey == "" {
+				return config, fmt.Errorf("启用 wechat_work 通知时 WECHAT_WORK_WEBHOOK_KEY 不能为空")
+			}
+		case "ntfy":
+			if u, err := url.Parse(config.NtfyURL); err != nil || u.Scheme == "" || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+				return config, fmt.Errorf("启用 ntfy 通知时 NTFY_URL 必须是包含主题的完整地址，如 https://ntfy.sh/cf-status")
+			}
+		default:
+			return config, fmt.Errorf("NOTIFIERS 包含未知的通知渠道: %s", name)
+		}
+	}
+	if u, err := url.Parse(config.DingtalkBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return config, fmt.Errorf("DINGTALK_BASE_URL 不是有效的 http(s) 地址: %s", config.DingtalkBaseURL)
+	}
+	if u, err := url.Parse(config.StatusPageURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return config, fmt.Errorf("STATUS_PAGE_URL 不是有效的 URL: %s", config.StatusPageURL)
+	}
+	if len(config.StatusPages) == 0 {
+		config.StatusPages = []string{config.StatusPageURL}
+	}
+	for _, page := range config.StatusPages {
+		if u, err := url.Parse(page); err != nil || u.Scheme == "" || u.Host == "" {
+			return config, fmt.Errorf("STATUS_PAGES 包含无效的 URL: %s", page)
+		}
+	}
+	monitored := make(map[string]bool, len(config.StatusPages))
+	for _, page := range config.StatusPages {
+		monitored[page] = true
+	}
+	for page := range config.PageLocales {
+		if !monitored[page] {
+			return config, fmt.Errorf("STATUS_PAGE_LOCALES 中的状态页 %s 不在监控的状态页列表中", page)
+		}
+	}
+	if config.FetchConcurrency <= 0 {
+		return config, fmt.Errorf("FETCH_CONCURRENCY 必须大于0")
+	}
+	if config.NotifyRetryCount < 0 {
+		return config, fmt.Errorf("NOTIFY_RETRY_COUNT 不能小于0")
+	}
+	if config.MaxConsecutiveFailures <= 0 {
+		return config, fmt.Errorf("MAX_CONSECUTIVE_FAILURES 必须大于0")
+	}
+	if config.DedupWindowMinutes <= 0 {
+		return config, fmt.Errorf("DEDUP_WINDOW_MINUTES 必须大于0")
+	}
+	if config.CacheRetentionDays <= 0 {
+		return config, fmt.Errorf("CACHE_RETENTION_DAYS 必须大于0")
+	}
+	if config.UptimeSLAThreshold <= 0 || config.UptimeSLAThreshold > 100 {
+		return config, fmt.Errorf("UPTIME_SLA_THRESHOLD 必须大于0且不超过100")
+	}
+	if config.StatusBoardRepostMinutes < 0 {
+		return config, fmt.Errorf("STATUS_BOARD_REPOST_MINUTES 不能小于0")
+	}
+	if err := validateTimeFormat(config.TimeFormat); err != nil {
+		return config, err
+	}
+	if config.ApprovalMode && !config.ObserveOnly {
+		if config.CheckTriggerToken == "" || config.HealthListenAddr == "" {
+			return config, fmt.Errorf("启用 APPROVAL_MODE 时必须配置 HEALTH_LISTEN_ADDR 和 CHECK_TRIGGER_TOKEN，以便通过 /approve 接口批准通知")
+		}
+		enabled := false
+		for _, name := range config.Notifiers {
+			enabled = enabled || name == config.ApprovalNotifier
+		}
+		if !enabled {
+			return config, fmt.Errorf("APPROVAL_NOTIFIER 必须是已在 NOTIFIERS 中启用的渠道")
+		}
+		if config.ApprovalTimeoutMinutes <= 0 {
+			return config, fmt.Errorf("APPROVAL_TIMEOUT_MINUTES 必须大于0")
+		}
+		if config.ApprovalExpireAction != approvalExpireSend && config.ApprovalExpireAction != approvalExpireDrop {
+			return config, fmt.Errorf("APPROVAL_EXPIRE_ACTION 必须是 send 或 drop")
+		}
+	}
+	if (config.QuietHoursStart < 0) != (config.QuietHoursEnd < 0) {
+		return config, fmt.Errorf("QUIET_HOURS_START 和 QUIET_HOURS_END 必须同时配置")
+	}
+	if config.QuietHoursStart > 23 || config.QuietHoursEnd > 23 {
+		return config, fmt.Errorf("QUIET_HOURS_START 和 QUIET_HOURS_END 必须在0-23之间")
+	}
+	if config.QuietHoursStart >= 0 && config.QuietHoursStart == config.QuietHoursEnd {
+		return config, fmt.Errorf("QUIET_HOURS_START 和 QUIET_HOURS_END 不能相同")
+	}
+	if config.UpdateDisplayMode != updateDisplayFull && config.UpdateDisplayMode != updateDisplayLatest {
+		return config, fmt.Errorf("UPDATE_DISPLAY_MODE 必须是 full 或 latest")
+	}
+	if config.NotifyRateLimit < 0 {
+		return config, fmt.Errorf("NOTIFY_RATE_LIMIT_PER_MINUTE 不能小于0")
+	}
+	if len(config.WatchComponents) > 0 {
+		// 订阅组件需要监控组件状态
+		config.MonitorComponents = true
+	}
+	if config.MaxUpdatesInDetail < 0 {
+		return config, fmt.Errorf("MAX_UPDATES_IN_DETAIL 不能小于0")
+	}
+	if _, ok := impactRank[config.MinImpactLevel]; !ok {
+		return config, fmt.Errorf("MIN_IMPACT_LEVEL 必须是 none、minor、major 或 critical")
+	}
+	if config.DuplicateIncidentPolicy != duplicatePolicyLatest && config.DuplicateIncidentPolicy != duplicatePolicyFirst {
+		return config, fmt.Errorf("DUPLICATE_INCIDENT_POLICY 必须是 latest 或 first")
+	}
+	for _, status := range config.NotifyStatuses {
+		if !knownIncidentStatuses[status] {
+			return config, fmt.Errorf("NOTIFY_STATUSES 中的 %s 不是有效的事件状态", status)
+		}
+	}
+	if len(config.SLAImpactLevels) == 0 {
+		return config, fmt.Errorf("SLA_IMPACT_LEVELS 不能为空")
+	}
+	for _, level := range config.SLAImpactLevels {
+		if _, ok := impactRank[level]; !ok {
+			return config, fmt.Errorf("SLA_IMPACT_LEVELS 中的 %s 不是有效的影响程度（none、minor、major 或 critical）", level)
+		}
+	}
+	if config.NotificationMode != notificationModeBatched && config.NotificationMode != notificationModeIndividual {
+		return config, fmt.Errorf("NOTIFICATION_MODE 必须是 batched 或 individual")
+	}
+	if config.LogFormat != logFormatText && config.LogFormat != logFormatJSON {
+		return config, fmt.Errorf("LOG_FORMAT 必须是 text 或 json")
+	}
+	if _, ok := logLevelRank[config.LogLevel]; !ok {
+		return config, fmt.Errorf("LOG_LEVEL 必须是 debug、info、warn 或 error")
+	}
+	if config.ReportSortOrder != reportSortTime && config.ReportSortOrder != reportSortImpact {
+		return config, fmt.Errorf("REPORT_SORT_ORDER 必须是 time 或 impact")
+	}
+	if config.ReportMaxIncidents < 0 {
+		return config, fmt.Errorf("REPORT_MAX_INCIDENTS 不能小于0")
+	}
+	if config.ReportDedupWindowMinutes < 0 {
+		return config, fmt.Errorf("REPORT_DEDUP_WINDOW_MINUTES 不能小于0")
+	}
+	if config.ReportDedupMode != reportDedupOmit && config.ReportDedupMode != reportDedupMark {
+		return config, fmt.Errorf("REPORT_DEDUP_MODE 必须是 omit 或 mark")
+	}
+	if config.PostResolutionQuietMinutes < 0 {
+		return config, fmt.Errorf("POST_RESOLUTION_QUIET_MINUTES 不能小于0")
+	}
+	if config.LongIncidentThresholdMinutes < 0 {
+		return config, fmt.Errorf("LONG_INCIDENT_THRESHOLD_MINUTES 不能小于0")
+	}
+	if config.LogMaxSizeMB < 0 {
+		return config, fmt.Errorf("LOG_MAX_SIZE_MB 不能小于0")
+	}
+
+	return config, nil
+}
+
+// 解析 "状态页地址=语言, 状态页地址=语言" 格式的状态页语言配置
+func parsePageLocales(value string) (map[string]string, error) {
+	locales := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// 状态页地址本身不含 "="，按最后一个 "=" 拆分
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 || strings.TrimSpace(entry[i+1:]) == "" {
+			return nil, fmt.Errorf("STATUS_PAGE_LOCALES 格式无效，应为 \"状态页地址=语言, 状态页地址=语言\": %s", entry)
+		}
+		page := normalizeStatusPageURL(entry[:i])
+		locales[page] = strings.TrimSpace(entry[i+1:])
+	}
+	return locales, nil
+}
+
+// 解析 "影响程度:名称, 影响程度:名称" 格式的影响程度展示名称
+func parseImpactLabels(value string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.Index(entry, ":")
+		if i <= 0 || strings.TrimSpace(entry[i+1:]) == "" {
+			return nil, fmt.Errorf("IMPACT_LABELS 格式无效，应为 \"影响程度:名称, 影响程度:名称\": %s", entry)
+		}
+		impact := strings.ToLower(strings.TrimSpace(entry[:i]))
+		if _, ok := impactRank[impact]; !ok {
+			return nil, fmt.Errorf("IMPACT_LABELS 中的 %s 不是有效的影响程度（none、minor、major 或 critical）", impact)
+		}
+		labels[impact] = strings.TrimSpace(entry[i+1:])
+	}
+	return labels, nil
+}
+
+// 解析 "名称: 值; 名称: 值" 格式的附加请求头
+func parseRequestHeaders(value string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("REQUEST_HEADERS 格式无效，应为 \"名称: 值; 名称: 值\": %s", entry)
+		}
+		headers.Add(name, strings.TrimSpace(parts[1]))
+	}
+	return headers, nil
+}
+
+// -validate 模式：校验模板文件并输出脱敏后的生效配置，校验失败时以退出码 1 结束
+func validateConfig(config Config) {
+	if config.TemplateFile != "" {
+		if _, err := loadTemplates(config.TemplateFile, config.TimeFormat, config.ImpactLabels); err != nil {
+			logFatalf("加载通知模板失败: %v", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(maskSecrets(config), "", "  ")
+	if err != nil {
+		logFatalf("输出配置失败: %v", err)
+	}
+	fmt.Println(string(data))
+	logInfof("配置校验通过")
+}
+
+// 返回密钥类字段已替换为 *** 的配置副本
+func maskSecrets(config Config) Config {
+	for _, secret := range []*string{
+		&config.DingtalkWebhookToken,
+		&config.DingtalkSecret,
+		&config.DingtalkCriticalWebhook,
+		&config.DingtalkCriticalSecret,
+		&config.DingtalkInfoWebhook,
+		&config.DingtalkInfoSecret,
+		&config.WebhookToken,
+		&config.WebhookSigningSecret,
+		&config.FeishuWebhook,
+		&config.FeishuSecret,
+		&config.WechatWorkWebhookKey,
+		&config.NtfyToken,
+		&config.CheckTriggerToken,
+	} {
+		if *secret != "" {
+			*secret = "***"
+		}
+	}
+	routes := make([]dingtalkRoute, len(config.DingtalkRoutes))
+	for i, route := range config.DingtalkRoutes {
+		routes[i] = dingtalkRoute{Keyword: route.Keyword, Token: "***"}
+		if route.Secret != "" {
+			routes[i].Secret = "***"
+		}
+	}
+	config.DingtalkRoutes = routes
+	config.WebhookURL = maskURL(config.WebhookURL)
+	config.NtfyURL = maskURL(config.NtfyURL)
+	config.DingtalkBaseURL = maskURL(config.DingtalkBaseURL)
+	return config
+}
+
+// 隐藏地址中可能包含凭据的部分：用户信息替换为 ***，查询参数只保留参数名。
+// 无法解析的地址整体替换为 ***
+func maskURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "***"
+	}
+	hasUserinfo := u.User != nil
+	u.User = nil
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			if name := strings.SplitN(param, "=", 2)[0]; name != "" {
+				params[i] = name + "=***"
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	// url.User 会转义 *，直接拼接到 // 之后
+	if hasUserinfo {
+		return strings.Replace(u.String(), "//", "//***@", 1)
+	}
+	return u.String()
+}
+
+// 打开配置来源："-" 表示从标准输入读取，http:// 或 https:// 开头时在启动时通过 HTTP 获取一次，
+// 其余视为本地文件路径
+func openConfigSource(configPath string) (io.ReadCloser, error) {
+	if configPath == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	if strings.HasPrefix(configPath, "http://") || strings.HasPrefix(configPath, "https://") {
+		resp, err := statusPageClient.Get(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("获取远程配置失败: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("获取远程配置失败: HTTP 状态码 %d", resp.StatusCode)
+		}
+		return resp.Body, nil
+	}
+	file, err := os.Open(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开配置文件失败: %v", err)
+	}
+	return file, nil
+}
+
+// 确定密钥的来源：配置了 <name>_FILE 时从文件读取并去掉末尾换行，两种来源不能同时配置
+func resolveSecret(name, inline, path string) (string, error) {
+	if path == "" {
+		return inline, nil
+	}
+	if inline != "" {
+		return "", fmt.Errorf("%s 和 %s_FILE 只能配置其中一个", name, name)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取 %s_FILE 失败: %v", name, err)
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("%s_FILE 指向的文件内容为空: %s", name, path)
+	}
+	return value, nil
+}
+
+var dingtalkTokenPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// 从粘贴的完整 Webhook 地址中提取 access_token 参数的值，rest 为 token 之后的剩余内容
+func extractAccessToken(value string) (token, rest string, ok bool) {
+	const param = "access_token="
+	idx := strings.Index(value, param)
+	if idx < 0 {
+		return "", "", false
+	}
+	value = value[idx+len(param):]
+	end := 0
+	for end < len(value) && (value[end] >= '0' && value[end] <= '9' || value[end] >= 'a' && value[end] <= 'z' || value[end] >= 'A' && value[end] <= 'Z') {
+		end++
+	}
+	return value[:end], value[end:], end > 0
+}
+
+// 校验钉钉 access_token 只包含字母和数字。误填了完整的 Webhook 地址时自动提取其中的 access_token 并给出警告
+func normalizeDingtalkToken(name, value string) (string, error) {
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	if value == "" {
+		return value, nil
+	}
+	if token, _, ok := extractAccessToken(value); ok {
+		logWarnf("%s 配置的是完整的 Webhook 地址，已自动提取其中的 access_token，建议只填写 access_token", name)
+		value = token
+	}
+	if !dingtalkTokenPattern.MatchString(value) {
+		return "", fmt.Errorf("%s 不是有效的钉钉 access_token（应只包含字母和数字），请填写 Webhook 地址中 access_token= 后面的部分", name)
+	}
+	return value, nil
+}
+
+// 解析逗号分隔的配置列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// dingtalkResponse 钉钉机器人接口的响应
+type dingtalkResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// dingtalkError 钉钉返回的业务错误
+type dingtalkError struct {
+	Code int
+	Msg  string
+	// 响应中 Retry-After 头指定的等待时间，为零时按指数退避重试
+	RetryAfter time.Duration
+}
+
+func (e *dingtalkError) Error() string {
+	return fmt.Sprintf("钉钉返回错误: errcode=%d, errmsg=%s", e.Code, e.Msg)
+}
+
+// 认证类钉钉错误码：access_token 无效或不存在、签名/关键词/IP 白名单校验失败。
+// 这类错误不会因重试而恢复，通常意味着机器人凭证被轮换
+func (e *dingtalkError) authFailure() bool {
+	switch e.Code {
+	case 300001, 300005, 310000, 40014:
+		return true
+	}
+	return false
+}
+
+// 可重试的钉钉错误码：系统繁忙和发送频率超限
+func (e *dingtalkError) retryable() bool {
+	switch e.Code {
+	case -1, 130101, 130102, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// 发送钉钉通知。熔断器打开时直接返回错误，由调用方决定是否加入投递队列；
+// 钉钉返回不可重试的业务错误说明接口本身可用，不计入熔断失败次数。rt 为发送渠道创建时的配置
+func (s *Service) sendDingtalkNotification(ctx context.Context, rt *runtimeConfig, target dingtalkTarget, title, content string, atAll bool) error {
+	if rt.dingtalkBreaker == nil {
+		return s.sendDingtalkWithRetry(ctx, rt, target, title, content, atAll)
+	}
+	if err := rt.dingtalkBreaker.Allow(); err != nil {
+		return err
+	}
+	err := s.sendDingtalkWithRetry(ctx, rt, target, title, content, atAll)
+	var dtErr *dingtalkError
+	rt.dingtalkBreaker.Record(err != nil && !(errors.As(err, &dtErr) && !dtErr.retryable()))
+	return err
+}
+
+func (s *Service) sendDingtalkWithRetry(ctx context.Context, rt *runtimeConfig, target dingtalkTarget, title, content string, atAll bool) error {
+	logDebugf("准备发送钉钉通知 - 机器人: %s, 标题: %s", target.name, title)
+
+	// 关键词模式下钉钉会拒绝不包含关键词的消息
+	if rt.config.DingtalkSecurityMode == dingtalkSecurityKeyword {
+		keyword := rt.config.DingtalkKeyword
+		if !strings.Contains(title, keyword) {
+			title = fmt.Sprintf("[%s] %s", keyword, title)
+		}
+		if !strings.Contains(content, keyword) {
+			content += "\n\n关键词: " + keyword
+		}
+	}
+
+	message := DingtalkMessage{
+		Msgtype: "markdown",
+	}
+	message.Markdown.Title = title
+	message.Markdown.Text = content
+	message.At.IsAtAll = atAll
+
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		logErrorf("生成钉钉消息 JSON 失败: %v", err)
+		return err
+	}
+	logDebugf("钉钉消息 JSON 生成成功，长度: %d 字节", len(jsonData))
+
+	maxAttempts := rt.config.NotifyRetryCount + 1
+	for attempt := 1; ; attempt++ {
+		err := s.postDingtalkMessage(ctx, rt, target, title, jsonData)
+		if err == nil {
+			return nil
+		}
+
+		logEvent("error", "dingtalk", logFields{"title": title, "attempt": attempt, "error": err.Error()},
+			"钉钉通知第 %d/%d 次发送失败: %v", attempt, maxAttempts, err)
+
+		var dtErr *dingtalkError
+		if errors.As(err, &dtErr) && !dtErr.retryable() {
+			return err
+		}
+		if attempt >= maxAttempts {
+			return fmt.Errorf("钉钉通知重试 %d 次后仍然失败: %v", attempt, err)
+		}
+
+		delay := retryBackoff(attempt)
+		if dtErr != nil && dtErr.RetryAfter > 0 {
+			// 按服务端要求的时间等待，同时暂停限流器，避免其他消息在等待期间继续触发限流
+			delay = dtErr.RetryAfter
+			logEvent("warn", "dingtalk", logFields{"title": title, "retry_after_ms": delay.Milliseconds()},
+				"钉钉要求 %v 后重试（Retry-After），将在此之后进行第 %d 次重试", delay, attempt+1)
+			if rt.dingtalkLimiter != nil {
+				rt.dingtalkLimiter.Pause(delay)
+			}
+		} else {
+			logWarnf("将在 %v 后进行第 %d 次重试", delay, attempt+1)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("钉钉通知重试被取消: %v", ctx.Err())
+		}
+	}
+}
+
+// 计算第 attempt 次失败后的等待时间：指数退避并叠加随机抖动
+func retryBackoff(attempt int) time.Duration {
+	delay := time.Second << uint(attempt-1)
+	if delay > 30*time.Second {
+		delay = 30 * time.Second
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// 钉钉开放接口的默认地址，机器人消息发送到其下的 /robot/send
+const defaultDingtalkBaseURL = "https://oapi.dingtalk.com"
+
+// 发送一次钉钉请求，每次都重新生成时间戳和签名
+func (s *Service) postDingtalkMessage(ctx context.Context, rt *runtimeConfig, target dingtalkTarget, title string, jsonData []byte) error {
+	if rt.dingtalkLimiter != nil {
+		delay, err := rt.dingtalkLimiter.Wait(ctx)
+		if err != nil {
+			return fmt.Errorf("等待钉钉发送频率限制时已取消: %v", err)
+		}
+		if delay > 0 {
+			logEvent("warn", "dingtalk", logFields{"title": title, "delay_ms": delay.Milliseconds()},
+				"钉钉发送频率达到上限，已等待 %v", delay)
+		}
+	}
+
+	webhookURL := fmt.Sprintf("%s/robot/send?access_token=%s", rt.config.DingtalkBaseURL, target.token)
+	if rt.config.DingtalkSecurityMode == dingtalkSecuritySign {
+		timestamp := strconv.FormatInt(s.Now().UnixMilli(), 10)
+		sign := generateDingtalkSign(timestamp, target.secret)
+		logDebugf("生成钉钉签名成功，时间戳: %s", timestamp)
+		webhookURL += fmt.Sprintf("&timestamp=%s&sign=%s", timestamp, url.QueryEscape(sign))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("创建钉钉请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logEvent("error", "dingtalk", logFields{"title": title, "error": err.Error()},
+			"发送钉钉 HTTP 请求失败: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 读取响应内容
+	respBody, err := readLimited(resp.Body, rt.config.MaxResponseBytes)
+	if err != nil {
+		logErrorf("读取钉钉响应失败: %v", err)
+		return err
+	}
+	logEvent("debug", "dingtalk", logFields{"title": title, "http_status": resp.StatusCode},
+		"钉钉响应: HTTP状态码=%d, 响应内容=%s", resp.StatusCode, string(respBody))
+
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), s.Now())
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &dingtalkError{Code: resp.StatusCode, Msg: "Too Many Requests", RetryAfter: retryAfter}
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("钉钉返回异常状态码: %d", resp.StatusCode)
+	}
+
+	var result dingtalkResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("解析钉钉响应失败: %v", err)
+	}
+	if result.ErrCode != 0 {
+		return &dingtalkError{Code: result.ErrCode, Msg: result.ErrMsg, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Retry-After 最长等待时间，避免异常的响应头让发送长时间阻塞
+const maxRetryAfter = 5 * time.Minute
+
+// 解析 Retry-After 响应头，支持秒数和 HTTP 日期两种格式，无效或缺失时返回 0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = at.Sub(now)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	if delay > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return delay
+}
+
+func generateDingtalkSign(timestamp, secret string) string {
+	return hmacSHA256Base64(secret, []byte(timestamp+"\n"+secret))
+}
+
+// 使用密钥计算消息的 HMAC-SHA256，返回 Base64 编码的签名
+func hmacSHA256Base64(secret string, message []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(message)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// pageResult 单个状态页的获取结果
+type pageResult struct {
+	page        string
+	incidents   []Incident
+	notModified bool // 状态页返回 304，incidents 来自上次的缓存
+	err         error
+}
+
+// pageCacheEntry 状态页上次成功响应的缓存验证信息和事件，用于条件请求
+type pageCacheEntry struct {
+	etag         string
+	lastModified string
+	incidents    []Incident
+}
+
+// 获取并处理事件，返回检测到的事件变化数量。定时检查和手动触发的检查不会并发执行
+func (s *Service) fetchAndProcessIncidents(ctx context.Context) (int, error) {
+	s.checkMutex.Lock()
+	defer s.checkMutex.Unlock()
+	// 本轮检查的开关统一取自同一份配置
+	rt := s.snapshot()
+
+	s.retryQueuedNotifications(ctx)
+	s.expireApprovals(ctx)
+
+	incidents, unchanged, err := s.fetchAllPages(ctx)
+	s.recordFetchResult(ctx, err)
+	if err != nil {
+		return 0, err
+	}
+	if rt.config.DebugDumpDir != "" {
+		s.dumpIncidents(incidents, s.Now())
+	}
+
+	// 预热期间每次成功获取都重新初始化缓存，最后一次预热检查按首次运行发送启动通知
+	warming := s.warmupRemaining > 0
+	if warming {
+		s.warmupRemaining--
+		s.mutex.Lock()
+		s.lastIncidents = nil
+		s.mutex.Unlock()
+		logInfof("预热检查 %d/%d，获取到 %d 个事件", rt.config.WarmupChecks-s.warmupRemaining, rt.config.WarmupChecks, len(incidents))
+	}
+	stillWarming := s.warmupRemaining > 0
+
+	// 所有状态页都返回 304 时跳过变化检测；仍有延迟通知等待发送时照常检测
+	s.mutex.RLock()
+	pending := len(s.deferredChanges) > 0
+	s.mutex.RUnlock()
+	changeCount := 0
+	if stillWarming {
+		// 只填充缓存，丢弃生成的通知
+		s.detectChanges(incidents)
+	} else if unchanged && !pending && !warming {
+		logDebugf("状态页数据未变化（304 Not Modified），跳过变化检测")
+	} else {
+		if rt.config.IncludeComponentSummary {
+			s.refreshComponentSummary(ctx)
+		}
+		if rt.config.ConfirmCriticalAlerts {
+			incidents = s.confirmHighSeverityChanges(ctx, incidents)
+		}
+		// 检查变化并发送通知
+		changeCount = s.checkForChanges(ctx, incidents)
+	}
+	if !stillWarming {
+		s.checkLongIncidents(ctx)
+
+		if rt.config.MonitorComponents {
+			s.checkComponents(ctx)
+		}
+		if rt.config.MonitorOverallStatus {
+			s.checkOverallStatus(ctx)
+		}
+		if rt.config.MonitorUptimeSLA {
+			s.checkUptimeSLA(ctx)
+		}
+		if rt.config.StatusBoard {
+			s.updateStatusBoard(ctx)
+		}
+	}
+
+	if rt.config.StateFile != "" {
+		if err := s.saveState(); err != nil {
+			logErrorf("保存状态失败: %v", err)
+		}
+	}
+
+	now := s.Now()
+	s.mutex.Lock()
+	s.lastCheckTime = now
+	s.mutex.Unlock()
+	if s.history != nil {
+		if err := s.history.SetLastSeen(now); err != nil {
+			logErrorf("记录最近检查时间失败: %v", err)
+		}
+	}
+	return changeCount, nil
+}
+
+// 记录获取结果，连续失败达到阈值时发送降级告警，恢复后发送恢复通知
+func (s *Service) recordFetchResult(ctx context.Context, fetchErr error) {
+	s.mutex.Lock()
+	var notification *Notification
+	if fetchErr != nil {
+		s.consecutiveFailures++
+		logWarnf("连续获取失败次数: %d", s.consecutiveFailures)
+		if s.consecutiveFailures >= s.config().MaxConsecutiveFailures && !s.degradedAlertSent {
+			s.degradedAlertSent = true
+			notification = &Notification{
+				Kind:  notifyKindHealth,
+				Title: "Cloudflare 状态监控降级",
+				Content: fmt.Sprintf("# Cloudflare 状态监控降级\n\n时间: %s\n\n"+
+					"状态监控已降级：连续 %d 次无法获取 Cloudflare 状态数据。\n\n最近一次错误: %v\n",
+					s.formatTime(s.Now()), s.consecutiveFailures, fetchErr),
+			}
+		}
+	} else {
+		if s.degradedAlertSent {
+			notification = &Notification{
+				Kind:  notifyKindHealth,
+				Title: "Cloudflare 状态监控已恢复",
+				Content: fmt.Sprintf("# Cloudflare 状态监控已恢复\n\n时间: %s\n\n"+
+					"在连续 %d 次获取失败后，已重新成功获取 Cloudflare 状态数据。\n",
+					s.formatTime(s.Now()), s.consecutiveFailures),
+			}
+		}
+		s.consecutiveFailures = 0
+		s.degradedAlertSent = false
+	}
+	s.mutex.Unlock()
+
+	if notification == nil {
+		return
+	}
+	if err := s.notify(ctx, *notification); err != nil {
+		logErrorf("发送监控健康通知失败: %v", err)
+	}
+}
+
+// 获取所有状态页的事件并按时间排序，unchanged 表示所有状态页均返回 304
+func (s *Service) fetchAllPages(ctx context.Context) (incidents []Incident, unchanged bool, err error) {
+	pages := s.config().StatusPages
+	logEvent("debug", "fetch", logFields{"page_count": len(pages)}, "开始获取状态数据，共 %d 个状态页...", len(pages))
+
+	// 使用固定大小的工作池并发获取各状态页，单个页面失败不影响其他页面
+	results := make([]pageResult, len(pages))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := s.config().FetchConcurrency
+	if workers > len(pages) {
+		workers = len(pages)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				incidents, notModified, err := s.fetchPageIncidents(ctx, pages[i])
+				results[i] = pageResult{page: pages[i], incidents: incidents, notModified: notModified, err: err}
+			}
+		}()
+	}
+	for i := range pages {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// 按配置顺序汇总，保证合并后的通知顺序稳定
+	var failed []string
+	unchanged = true
+	for _, result := range results {
+		if result.err != nil {
+			failed = append(failed, result.page)
+			continue
+		}
+		unchanged = unchanged && result.notModified
+		incidents = append(incidents, result.incidents...)
+	}
+	if len(failed) == len(pages) {
+		return nil, false, fmt.Errorf("所有状态页获取失败: %s", strings.Join(failed, ", "))
+	}
+	if len(failed) > 0 {
+		logEvent("warn", "fetch", logFields{"failed_pages": failed}, "部分状态页获取失败: %s", strings.Join(failed, ", "))
+	}
+
+	incidents = dedupeIncidents(incidents, s.config().DuplicateIncidentPolicy)
+
+	// 按时间排序
+	sort.SliceStable(incidents, func(i, j int) bool {
+		return incidents[i].CreatedAt.After(incidents[j].CreatedAt)
+	})
+	logDebugf("事件按时间排序完成")
+	return incidents, unchanged && len(failed) == 0, nil
+}
+
+// statusPageClient 请求状态页使用的共享 HTTP 客户端
+var statusPageClient = &http.Client{Timeout: 30 * time.Second}
+
+// 使用共享客户端请求状态页 page 的 path 接口，附带配置的 User-Agent、附加请求头和 extra 中的请求头；
+// 该状态页配置了语言时通过 Accept-Language（及可选的查询参数）请求本地化内容
+func (s *Service) getStatusPage(ctx context.Context, page, path string, extra http.Header) (*http.Response, error) {
+	target := page + path
+	locale := s.config().PageLocales[page]
+	if locale != "" && s.config().PageLocaleQueryParam != "" {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		query := u.Query()
+		query.Set(s.config().PageLocaleQueryParam, locale)
+		u.RawQuery = query.Encode()
+		target = u.String()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, headers := range []http.Header{s.config().RequestHeaders, extra} {
+		for name, values := range headers {
+			for _, value := range values {
+				req.Header.Add(name, value)
+			}
+		}
+	}
+	if s.config().UserAgent != "" {
+		req.Header.Set("User-Agent", s.config().UserAgent)
+	}
+	// 不支持本地化的状态页会忽略该请求头，返回默认语言的内容
+	if locale != "" {
+		req.Header.Set("Accept-Language", locale)
+	}
+	// 显式请求 gzip 压缩以减少每轮轮询的流量，REQUEST_HEADERS 中已指定时保持不变
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	resp, err := statusPageClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	decompressResponse(resp)
+	return resp, nil
+}
+
+// 读取响应内容，超过 limit 字节时返回错误而不是截断，避免把不完整的 JSON 当作有效数据
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("响应内容超过 MAX_RESPONSE_BYTES 限制（%d 字节）", limit)
+	}
+	return body, nil
+}
+
+// 获取单个状态页的事件。携带上次响应的 ETag/Last-Modified 发起条件请求，
+// 返回 304 时直接使用缓存的事件，notModified 为 true
+func (s *Service) fetchPageIncidents(ctx context.Context, page string) (incidents []Incident, notModified bool, err error) {
+	logEvent("debug", "fetch", logFields{"page": page}, "开始获取状态页数据: %s", page)
+
+	s.mutex.RLock()
+	cached, hasCache := s.pageCache[page]
+	s.mutex.RUnlock()
+	conditional := make(http.Header)
+	if hasCache {
+		if cached.etag != "" {
+			conditional.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			conditional.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := s.getStatusPage(ctx, page, "/api/v2/incidents.json", conditional)
+	if err != nil {
+		logEvent("error", "fetch", logFields{"page": page, "error": err.Error()}, "HTTP 请求失败: %v", err)
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	logDebugf("成功获取 HTTP 响应，状态页: %s，状态码: %d", page, resp.StatusCode)
+
+	if resp.StatusCode == http.StatusNotModified && hasCache {
+		logDebugf("状态页 %s 无变化（304 Not Modified）", page)
+		return cached.incidents, true, nil
+	}
+
+	// 获取并保存版本信息
+	if version := resp.Header.Get("X-Statuspage-Version"); version != "" {
+		s.mutex.Lock()
+		s.statusVersion = version
+		s.mutex.Unlock()
+		logDebugf("获取到新的 X-Statuspage-Version: %s", version)
+	}
+
+	body, err := readLimited(resp.Body, s.config().MaxResponseBytes)
+	if err != nil {
+		logErrorf("读取响应内容失败: %v", err)
+		return nil, false, err
+	}
+	logDebugf("成功读取响应内容，数据长度: %d 字节", len(body))
+	if compressed, decompressed, ok := compressionStats(resp); ok && decompressed > 0 {
+		logDebugf("响应使用 gzip 压缩，压缩后 %d 字节，解压后 %d 字节，压缩率 %.1f%%",
+			compressed, decompressed, float64(compressed)*100/float64(decompressed))
+	}
+
+	incidents, err = decodeIncidents(page, body)
+	if err != nil {
+		logEvent("error", "fetch", logFields{"page": page, "error": err.Error()}, "解析响应失败: %v", err)
+		return nil, false, err
+	}
+	logEvent("debug", "fetch", logFields{"page": page, "incident_count": len(incidents)},
+		"成功解析 JSON 数据，获取到 %d 个事件", len(incidents))
+
+	for i := range incidents {
+		incidents[i].Page = page
+	}
+
+	// 服务器支持条件请求时缓存验证信息，不支持时每次都完整获取
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	s.mutex.Lock()
+	if etag != "" || lastModified != "" {
+		if s.pageCache == nil {
+			s.pageCache = make(map[string]pageCacheEntry)
+		}
+		s.pageCache[page] = pageCacheEntry{etag: etag, lastModified: lastModified, incidents: incidents}
+	} else {
+		delete(s.pageCache, page)
+	}
+	s.mutex.Unlock()
+	return incidents, false, nil
+}
+
+// 更新历史展示模式
+const (
+	updateDisplayFull   = "full"
+	updateDisplayLatest = "latest"
+)
+
+// 变更通知模式
+const (
+	notificationModeBatched    = "batched"    // 所有变化合并为一条通知
+	notificationModeIndividual = "individual" // 每个变化单独发送一条通知
+)
+
+// 选择变更通知中展示的更新记录。latest 模式下只展示相对缓存新增的更新，
+// 没有新增时展示最近一条；old 为 nil 表示新事件。
+// 关闭 SHOW_UPDATE_HISTORY 时不展示更新，已解决的事件仍展示解决时的那条更新
+func (s *Service) displayUpdates(incident Incident, old *Incident) []Update {
+	if !s.config().ShowUpdateHistory {
+		if !incident.isResolved() {
+			return nil
+		}
+		return resolvingUpdate(incident)
+	}
+	if s.config().UpdateDisplayMode == updateDisplayFull || len(incident.IncidentUpdates) == 0 {
+		return incident.IncidentUpdates
+	}
+
+	if old != nil {
+		seen := make(map[string]bool, len(old.IncidentUpdates))
+		for _, update := range old.IncidentUpdates {
+			seen[update.ID] = true
+		}
+		var fresh []Update
+		for _, update := range incident.IncidentUpdates {
+			if !seen[update.ID] {
+				fresh = append(fresh, update)
+			}
+		}
+		if len(fresh) > 0 {
+			return fresh
+		}
+	}
+
+	latest, _ := incident.latestUpdate()
+	return []Update{latest}
+}
+
+// 事件解决时的更新：最近一条状态为 resolved 的更新，没有时为最近一条更新
+func resolvingUpdate(incident Incident) []Update {
+	var resolving *Update
+	for i, update := range incident.IncidentUpdates {
+		if update.Status == "resolved" && (resolving == nil || update.CreatedAt.After(resolving.CreatedAt)) {
+			resolving = &incident.IncidentUpdates[i]
+		}
+	}
+	if resolving != nil {
+		return []Update{*resolving}
+	}
+	if latest, ok := incident.latestUpdate(); ok {
+		return []Update{latest}
+	}
+	return nil
+}
+
+// 影响程度对应的钉钉 Markdown 字体颜色
+var impactColors = map[string]string{
+	"critical": "#FF0000",
+	"major":    "#FF8C00",
+	"minor":    "#FFD700",
+	"none":     "#808080",
+}
+
+// 事件状态对应的图标
+var statusEmojis = map[string]string{
+	"investigating": "🔴",
+	"identified":    "🔴",
+	"monitoring":    "🟡",
+	"resolved":      "🟢",
+	"postmortem":    "🟢",
+}
+
+// 生成事件标题前的状态图标和彩色影响程度标记
+func incidentBadge(incident Incident) string {
+	return impactBadge(incident.Status, incident.Impact, strings.ToUpper(incident.Impact))
+}
+
+// 生成状态图标和彩色影响程度标记，颜色按 impact 选择，标记中展示 label
+func impactBadge(status, impact, label string) string {
+	var badge strings.Builder
+	if emoji, ok := statusEmojis[status]; ok {
+		badge.WriteString(emoji + " ")
+	}
+	color, ok := impactColors[impact]
+	if !ok {
+		color = impactColors["none"]
+	}
+	badge.WriteString(fmt.Sprintf("<font color=\"%s\">[%s]</font> ", color, label))
+	return badge.String()
+}
+
+// 通知中展示的影响程度，配置了 IMPACT_LABELS 时使用对应名称，否则为原始值。过滤和排序仍按原始值
+func (s *Service) impactLabel(impact string) string {
+	if label, ok := s.config().ImpactLabels[impact]; ok {
+		return label
+	}
+	return impact
+}
+
+// 影响程度标记中展示的名称，未配置 IMPACT_LABELS 时为大写的原始值
+func (s *Service) impactBadgeLabel(impact string) string {
+	if label, ok := s.config().ImpactLabels[impact]; ok {
+		return label
+	}
+	return strings.ToUpper(impact)
+}
+
+// 生成单行的事件摘要，如 "🔴 [critical] Workers API errors — investigating ([详情](链接))"
+func (s *Service) formatIncidentCompact(incident Incident) string {
+	var line strings.Builder
+	if emoji, ok := statusEmojis[incident.Status]; ok {
+		line.WriteString(emoji + " ")
+	}
+	line.WriteString(fmt.Sprintf("[%s] %s — %s ([详情](%s))", s.impactLabel(incident.Impact), incident.Name, incident.Status, s.incidentLink(incident)))
+	return line.String()
+}
+
+// 生成事件详情，updates 为需要展示的更新记录
+func (s *Service) formatIncidentDetails(incident Incident, updates []Update) string {
+	return dingtalkRenderer{}.renderIncident(s.incidentDoc(incident, updates))
+}
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// 清理更新内容中的 HTML 和 Markdown 残留，使其可以安全地嵌入钉钉 Markdown 列表项。
+// 只用于展示，缓存中保留原始内容
+func sanitizeUpdateBody(body string) string {
+	body = htmlTagPattern.ReplaceAllString(body, "")
+	body = html.UnescapeString(body)
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		// 行首的标题或引用符号会破坏列表结构，需要转义
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ">") {
+			line = "\\" + line
+		}
+		lines = append(lines, line)
+	}
+	// 续行缩进以保持在同一个列表项内
+	return strings.Join(lines, "\n  ")
+}
+
+// 生成事件链接，shortlink 缺失或无效时根据状态页地址和事件 ID 拼接
+func incidentURL(baseURL string, incident Incident) string {
+	if incident.Shortlink != "" {
+		if link, err := url.Parse(incident.Shortlink); err == nil {
+			if link.IsAbs() && link.Host != "" {
+				return link.String()
+			}
+			if base, err := url.Parse(baseURL + "/"); err == nil && link.Path != "" {
+				return base.ResolveReference(link).String()
+			}
+		}
+		logWarnf("事件链接无效，使用默认链接 - ID: %s, shortlink: %s", incident.ID, incident.Shortlink)
+	}
+	return fmt.Sprintf("%s/incidents/%s", baseURL, url.PathEscape(incident.ID))
+}
+
+// 生成事件所属状态页下的事件链接
+func (s *Service) incidentLink(incident Incident) string {
+	page := incident.Page
+	if page == "" {
+		page = s.config().StatusPageURL
+	}
+	return incidentURL(page, incident)
+}
+
+// 生成通知头部，timestamp 为已格式化的当前时间，调用方需持有锁以安全读取 version
+func notificationHeader(version, timestamp string) string {
+	var header strings.Builder
+	header.WriteString(fmt.Sprintf("时间: %s\n\n", timestamp))
+	if version != "" {
+		header.WriteString(fmt.Sprintf("X-Statuspage-Version: %s\n", version))
+	}
+	header.WriteString("\n")
+	return header.String()
+}
+
+// 检查事件变化并发送通知。
+// 锁只覆盖 detectChanges 中对 lastIncidents 的读写，通知内容在持锁期间生成为局部变量；
+// 发送阶段只读取这些局部变量和通过 snapshot 获取的只读配置。SIGHUP 或 POST /reload 重新加载时发布新的配置快照，
+// 不修改正在使用的那一份，因此在锁外进行网络 I/O 不会引入数据竞争。
+func (s *Service) checkForChanges(ctx context.Context, incidents []Incident) int {
+	notifications, changeCount := s.detectChanges(incidents)
+
+	if s.history != nil {
+		if err := s.history.SaveIncidents(incidents); err != nil {
+			logErrorf("写入事件历史失败: %v", err)
+		}
+	}
+
+	for _, notification := range notifications {
+		if s.config().ApprovalMode && !s.config().ObserveOnly {
+			s.holdForApproval(ctx, notification)
+			continue
+		}
+		if err := s.notify(ctx, notification); err != nil {
+			logEvent("error", "notify", logFields{"kind": notification.Kind, "error": err.Error()}, "发送通知失败: %v", err)
+		} else {
+			logEvent("info", "notify", logFields{"kind": notification.Kind, "change_count": len(notification.Events)}, "通知发送成功")
+			s.recordNotified(notification)
+		}
+	}
+	return changeCount
+}
+
+// 在持锁状态下更新事件缓存，返回需要发送的通知和检测到的变化数量
+func (s *Service) detectChanges(incidents []Incident) ([]Notification, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	logDebugf("开始检查事件变化...")
+
+	// 限制事件数量为配置的最大值，优先保留未解决和影响程度高的事件
+	if len(incidents) > s.config().MaxIncidents {
+		logWarnf("事件数量超过配置的最大值 %d，将优先处理未解决和影响程度高的 %d 个事件",
+			s.config().MaxIncidents, s.config().MaxIncidents)
+		incidents = append([]Incident(nil), incidents...)
+		sortByRetentionPriority(incidents)
+		incidents = incidents[:s.config().MaxIncidents]
+		// 恢复按创建时间倒序，保持通知中事件的顺序
+		sort.SliceStable(incidents, func(i, j int) bool {
+			return incidents[i].CreatedAt.After(incidents[j].CreatedAt)
+		})
+	}
+	logDebugf("当前处理的事件数量: %d", len(incidents))
+
+	// 第一次运行时初始化并发送通知
+	if s.lastIncidents == nil {
+		logInfof("首次运行，初始化事件缓存...")
+		s.lastIncidents = make(map[string]Incident)
+
+		var firstRunNotification strings.Builder
+		firstRunNotification.WriteString("# Cloudflare 状态监控启动\n\n")
+		firstRunNotification.WriteString(fmt.Sprintf("时间: %s\n", s.formatTime(s.Now())))
+		if s.statusVersion != "" {
+			firstRunNotification.WriteString(fmt.Sprintf("X-Statuspage-Version: %s\n", s.statusVersion))
+		}
+		firstRunNotification.WriteString("\n")
+
+		// 缓存全部事件以便后续比较变化。通知中列出所有未解决的事件（包括创建时间早于回溯窗口的长期事件），
+		// 已解决的事件默认不列出，开启 STARTUP_INCLUDE_RESOLVED 时只列出回溯窗口内的
+		lookback := s.lookbackStart()
+		var listed []Incident
+		omittedResolved := 0
+		for _, incident := range incidents {
+			logDebugf("处理初始事件 - ID: %s, 名称: %s, 状态: %s",
+				incident.ID, incident.Name, incident.Status)
+			s.lastIncidents[incident.ID] = incident
+			switch {
+			case incident.isResolved() && !incident.CreatedAt.After(lookback):
+				logDebugf("首次运行通知跳过较早的已解决事件 - ID: %s, 创建时间: %s",
+					incident.ID, incident.CreatedAt.Format("2006-01-02 15:04:05"))
+			case incident.isResolved() && !s.config().StartupIncludeResolved:
+				omittedResolved++
+			default:
+				listed = append(listed, incident)
+			}
+		}
+
+		firstRunNotification.WriteString(s.activeSeveritySummary(listed))
+		if len(listed) > 0 {
+			if s.config().StartupIncludeResolved {
+				firstRunNotification.WriteString("## 当前及近期事件\n\n")
+			} else {
+				firstRunNotification.WriteString("## 当前活跃事件\n\n")
+			}
+			for _, incident := range listed {
+				firstRunNotification.WriteString(s.renderIncident(templateNew, incident, s.displayUpdates(incident, nil)))
+			}
+		} else {
+			logDebugf("初始化时没有发现活跃事件")
+			firstRunNotification.WriteString("当前没有活跃的事件。\n")
+		}
+		if omittedResolved > 0 {
+			firstRunNotification.WriteString(fmt.Sprintf("\n另有 %d 个近期已解决的事件未列出。\n", omittedResolved))
+		}
+
+		firstRunNotification.WriteString("\n---\n")
+		firstRunNotification.WriteString(s.notificationFooter(nil))
+
+		logDebugf("事件缓存初始化完成，共缓存 %d 个事件", len(s.lastIncidents))
+
+		if !s.config().SendStartupNotification {
+			logInfof("已关闭首次运行通知，跳过发送")
+			return nil, 0
+		}
+
+		return []Notification{{
+			Kind:    notifyKindStartup,
+			Title:   "Cloudflare 状态监控已启动",
+			Content: firstRunNotification.String(),
+		}}, 0
+	}
+
+	var changes []incidentChange
+	threeDaysAgo := s.lookbackStart()
+	logDebugf("设置时间范围：%s 之后的事件", threeDaysAgo.Format("2006-01-02 15:04:05"))
+
+	// 检查新事件和更新
+	for _, incident := range incidents {
+		if !incident.CreatedAt.After(threeDaysAgo) && !s.trackOldIncident(incident) {
+			logDebugf("跳过较早的事件 - ID: %s, 创建时间: %s",
+				incident.ID, incident.CreatedAt.Format("2006-01-02 15:04:05"))
+			continue
+		}
+
+		oldIncident, exists := s.lastIncidents[incident.ID]
+		if !exists {
+			if !incident.CreatedAt.After(threeDaysAgo) {
+				logInfof("发现创建时间早于回溯窗口的未解决事件 - ID: %s, 创建时间: %s",
+					incident.ID, incident.CreatedAt.Format("2006-01-02 15:04:05"))
+			}
+			logEvent("info", "detector", logFields{"incident_id": incident.ID, "change": "new"},
+				"发现新事件 - ID: %s, 名称: %s", incident.ID, incident.Name)
+			changes = append(changes, incidentChange{
+				Section: s.changeSection("## 新事件\n", "新事件", templateNew, incident, nil),
+				Event:   IncidentEvent{ChangeType: changeTypeNew, Incident: incident},
+			})
+		} else if s.inPostResolutionQuiet(oldIncident, incident) {
+			if oldIncident.UpdatedAt != incident.UpdatedAt || incident.hasNewUpdate(oldIncident) {
+				logInfof("已解决事件在静默期内的后续更新，只更新缓存不通知 - ID: %s, 名称: %s", incident.ID, incident.Name)
+			}
+		} else if edits, renames := s.updateEditsFor(oldIncident, incident), incidentRenames(oldIncident, incident); oldIncident.UpdatedAt != incident.UpdatedAt || len(edits) > 0 || len(renames) > 0 || incident.hasNewUpdate(oldIncident) {
+			logEvent("info", "detector", logFields{"incident_id": incident.ID, "change": "update", "status": incident.Status},
+				"事件更新 - ID: %s, 名称: %s, 新状态: %s", incident.ID, incident.Name, incident.Status)
+
+			if oldIncident.UpdatedAt == incident.UpdatedAt && incident.hasNewUpdate(oldIncident) {
+				logInfof("事件新增了更新记录但 updated_at 未变化 - ID: %s, 更新数: %d -> %d",
+					incident.ID, len(oldIncident.IncidentUpdates), len(incident.IncidentUpdates))
+			}
+			// 记录状态变化
+			if oldIncident.Status != incident.Status {
+				logDebugf("状态变化 - ID: %s, 旧状态: %s, 新状态: %s",
+					incident.ID, oldIncident.Status, incident.Status)
+			}
+
+			changeType, templateName := changeTypeUpdate, templateUpdate
+			if incident.isResolved() && !oldIncident.isResolved() {
+				changeType, templateName = changeTypeResolved, templateResolved
+			} else if oldIncident.isResolved() && incident.isActiveStatus() {
+				changeType = changeTypeReopened
+			}
+
+			// 影响程度升级单独标记，下降时只做说明
+			heading, label := "## 事件更新\n", "事件更新"
+			if changeType == changeTypeResolved {
+				label = "已解决"
+			}
+			escalated := false
+			oldImpact, newImpact := s.impactLabel(oldIncident.Impact), s.impactLabel(incident.Impact)
+			if oldRank, newRank := impactRank[oldIncident.Impact], impactRank[incident.Impact]; newRank > oldRank {
+				logEvent("warn", "detector", logFields{"incident_id": incident.ID, "change": "escalation",
+					"old_impact": oldIncident.Impact, "new_impact": incident.Impact},
+					"事件影响升级 - ID: %s, 影响程度: %s -> %s", incident.ID, oldIncident.Impact, incident.Impact)
+				heading = fmt.Sprintf("## ⚠️ 影响升级\n> 影响程度: %s → %s\n\n", oldImpact, newImpact)
+				label = fmt.Sprintf("⚠️ 影响升级（%s → %s）", oldImpact, newImpact)
+				escalated = true
+			} else if newRank < oldRank {
+				logInfof("事件影响下降 - ID: %s, 影响程度: %s -> %s", incident.ID, oldIncident.Impact, incident.Impact)
+				heading = fmt.Sprintf("## 事件更新\n> 影响程度已下降: %s → %s\n\n", oldImpact, newImpact)
+				label = fmt.Sprintf("影响下降（%s → %s）", oldImpact, newImpact)
+			}
+			// 重新开启的事件往往比首次发生更严重，与影响升级一样突破过滤并 @所有人
+			if changeType == changeTypeReopened {
+				logEvent("warn", "detector", logFields{"incident_id": incident.ID, "change": "reopened",
+					"old_status": oldIncident.Status, "new_status": incident.Status},
+					"事件重新开启 - ID: %s, 状态: %s -> %s", incident.ID, oldIncident.Status, incident.Status)
+				heading = fmt.Sprintf("## 🔁 事件重新开启\n> 状态: %s → %s\n\n", oldIncident.Status, incident.Status)
+				label = "🔁 重新开启"
+				if escalated {
+					heading = fmt.Sprintf("## 🔁 事件重新开启\n> 状态: %s → %s，影响程度: %s → %s\n\n",
+						oldIncident.Status, incident.Status, oldImpact, newImpact)
+					label = fmt.Sprintf("🔁 重新开启（影响 %s → %s）", oldImpact, newImpact)
+				}
+				escalated = true
+			}
+			if len(renames) > 0 {
+				logEvent("info", "detector", logFields{"incident_id": incident.ID, "change": "rename"},
+					"事件名称或链接变化 - ID: %s, %s", incident.ID, strings.Join(renames, "; "))
+				heading = strings.TrimRight(heading, "\n") + "\n"
+				for _, rename := range renames {
+					heading += "> " + rename + "\n"
+				}
+				heading += "\n"
+			}
+			section := s.changeSection(heading, label, templateName, incident, &oldIncident)
+			if changeType == changeTypeResolved && s.config().GenerateTimelines {
+				if link, err := s.writeTimeline(incident); err != nil {
+					logErrorf("生成事件时间线失败 - ID: %s, 错误: %v", incident.ID, err)
+				} else {
+					logInfof("已生成事件时间线 - ID: %s, 链接: %s", incident.ID, link)
+					section += fmt.Sprintf("> 完整时间线: %s\n\n", link)
+				}
+			}
+			if len(edits) > 0 && !s.config().CompactNotifications {
+				logInfof("事件更新内容被修改 - ID: %s, 修改的更新数: %d", incident.ID, len(edits))
+				section += s.formatUpdateEdits(edits)
+			}
+			changes = append(changes, incidentChange{
+				Section:   section,
+				Event:     IncidentEvent{ChangeType: changeType, Incident: incident},
+				Escalated: escalated,
+			})
+		} else {
+			logDebugf("事件无变化 - ID: %s, 名称: %s", incident.ID, incident.Name)
+		}
+		s.lastIncidents[incident.ID] = incident
+	}
+
+	// 清理超过保留期限的事件
+	retentionCutoff := s.Now().AddDate(0, 0, -s.config().CacheRetentionDays)
+	expiredCount := 0
+	for id, incident := range s.lastIncidents {
+		if s.cacheExpired(incident, retentionCutoff) {
+			logDebugf("清理过期事件 - ID: %s, 创建时间: %s",
+				id, incident.CreatedAt.Format("2006-01-02 15:04:05"))
+			delete(s.lastIncidents, id)
+			expiredCount++
+		}
+	}
+	if expiredCount > 0 {
+		logInfof("按保留期限（%d 天）清理了 %d 个事件", s.config().CacheRetentionDays, expiredCount)
+	}
+
+	// 清理超过最大数量的旧事件
+	if len(s.lastIncidents) > s.config().MaxIncidents {
+		logInfof("清理旧事件，当前缓存数量: %d，最大允许数量: %d",
+			len(s.lastIncidents), s.config().MaxIncidents)
+		var incidentSlice []Incident
+		for _, incident := range s.lastIncidents {
+			incidentSlice = append(incidentSlice, incident)
+		}
+		sortByRetentionPriority(incidentSlice)
+		newIncidents := make(map[string]Incident)
+		for i := 0; i < s.config().MaxIncidents && i < len(incidentSlice); i++ {
+			newIncidents[incidentSlice[i].ID] = incidentSlice[i]
+			logDebugf("保留事件 - ID: %s, 名称: %s",
+				incidentSlice[i].ID, incidentSlice[i].Name)
+		}
+		logInfof("按最大数量清理了 %d 个事件", len(s.lastIncidents)-len(newIncidents))
+		s.lastIncidents = newIncidents
+		logDebugf("清理完成，现有缓存数量: %d", len(s.lastIncidents))
+	}
+
+	logInfof("事件检查完成，发现 %d 个变化", len(changes))
+	changeCount := len(changes)
+
+	changes = s.filterChanges(changes)
+
+	var notifications []Notification
+
+	// 静默时段内只立即发送 critical 事件，其余变化进入延迟队列
+	if s.inQuietHours(s.Now().UTC()) {
+		var urgent []incidentChange
+		for _, change := range changes {
+			if impactRank[change.Event.Incident.Impact] >= impactRank["critical"] {
+				urgent = append(urgent, change)
+				continue
+			}
+			logInfof("静默时段内延迟通知 - ID: %s, 影响程度: %s",
+				change.Event.Incident.ID, change.Event.Incident.Impact)
+			s.deferredChanges = append(s.deferredChanges, change)
+		}
+		changes = urgent
+	} else if len(s.deferredChanges) > 0 {
+		logInfof("静默时段结束，发送 %d 个延迟的变化", len(s.deferredChanges))
+		notifications = append(notifications, s.buildRoutedNotifications(s.deferredChanges, func([]incidentChange) string {
+			return "Cloudflare 静默时段汇总"
+		})...)
+		s.deferredChanges = nil
+	}
+
+	if len(changes) == 0 {
+		logDebugf("没有需要立即发送的变化，跳过通知")
+		return notifications, changeCount
+	}
+
+	logDebugf("准备发送变更通知...")
+	if s.config().NotificationMode == notificationModeIndividual {
+		// 逐条发送时标题带上事件名称和影响程度，便于按标题路由；发送频率由各渠道的限流器控制
+		for _, change := range changes {
+			notifications = append(notifications, s.buildRoutedNotifications([]incidentChange{change}, func(group []incidentChange) string {
+				return s.changeTitle(group, true)
+			})...)
+		}
+		return notifications, changeCount
+	}
+	return append(notifications, s.buildRoutedNotifications(changes, func(group []incidentChange) string {
+		return s.changeTitle(group, false)
+	})...), changeCount
+}
+
+// 按保留优先级排序：未解决的事件在前，其次按影响程度从高到低，最后按创建时间从新到旧。
+// 截断到 MAX_INCIDENTS 时最先丢弃的是较早的已解决低影响事件
+func sortByRetentionPriority(incidents []Incident) {
+	sort.SliceStable(incidents, func(i, j int) bool {
+		a, b := incidents[i], incidents[j]
+		if a.isResolved() != b.isResolved() {
+			return !a.isResolved()
+		}
+		if rankA, rankB := impactRank[a.Impact], impactRank[b.Impact]; rankA != rankB {
+			return rankA > rankB
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+}
+
+// 事件名称或短链接与缓存中不同时的说明，如 "事件已重命名: 旧 → 新"。名称变化往往意味着事件范围被重新界定
+func incidentRenames(old, incident Incident) []string {
+	var renames []string
+	if old.Name != incident.Name && old.Name != "" {
+		renames = append(renames, fmt.Sprintf("事件已重命名: %s → %s", old.Name, incident.Name))
+	}
+	if old.Shortlink != incident.Shortlink && old.Shortlink != "" && incident.Shortlink != "" {
+		renames = append(renames, fmt.Sprintf("事件链接已变更: %s → %s", old.Shortlink, incident.Shortlink))
+	}
+	return renames
+}
+
+// 启用 SHOW_UPDATE_DIFFS 时返回内容被修改的更新记录
+func (s *Service) updateEditsFor(old, incident Incident) []updateEdit {
+	if !s.config().ShowUpdateDiffs {
+		return nil
+	}
+	return editedUpdates(old, incident)
+}
+
+// 判断已解决事件的更新是否处于 POST_RESOLUTION_QUIET_MINUTES 静默期内：
+// 事件解决前后状态未变化且距解决时间不足静默时长，状态变化（如重新开启、发布事后报告）不受影响
+func (s *Service) inPostResolutionQuiet(old, incident Incident) bool {
+	if s.config().PostResolutionQuietMinutes <= 0 || !old.isResolved() || !incident.isResolved() || old.Status != incident.Status {
+		return false
+	}
+	window := time.Duration(s.config().PostResolutionQuietMinutes) * time.Minute
+	return s.Now().Sub(incident.resolvedTime()) < window
+}
+
+// 生成一次变化的通知正文。紧凑模式下每个事件只占一行，以 label 标明变化类型；
+// 否则为 heading 加完整的事件详情
+func (s *Service) changeSection(heading, label, templateName string, incident Incident, old *Incident) string {
+	prefix := s.pagePrefix(incident)
+	if s.config().CompactNotifications {
+		return fmt.Sprintf("- %s%s: %s\n", prefix, label, s.formatIncidentCompact(incident))
+	}
+	heading = strings.Replace(heading, "## ", "## "+prefix, 1)
+	if old != nil {
+		heading += s.threadReference(incident)
+	}
+	return heading + s.renderIncident(templateName, incident, s.displayUpdates(incident, old))
+}
+
+// 过滤不需要通知的变化，调用方需持有锁。被过滤的事件仍然保留在缓存中
+func (s *Service) filterChanges(changes []incidentChange) []incidentChange {
+	filtered := changes[:0]
+	for _, change := range changes {
+		incident := change.Event.Incident
+		if !s.statusNotified(incident.Status) {
+			logDebugf("事件状态不在 NOTIFY_STATUSES 中，跳过通知 - ID: %s, 状态: %s", incident.ID, incident.Status)
+			continue
+		}
+		change = s.promoteFirstNotified(change)
+		forced := change.Escalated || (s.config().NotifyAllNewIncidents && change.Event.ChangeType == changeTypeNew)
+		if !forced && impactRank[incident.Impact] < impactRank[s.config().MinImpactLevel] {
+			logDebugf("事件影响程度低于 %s，跳过通知 - ID: %s, 影响程度: %s",
+				s.config().MinImpactLevel, incident.ID, incident.Impact)
+			continue
+		}
+		if len(s.config().RegionKeywords) > 0 {
+			keyword, ok := matchRegionKeyword(incident, s.config().RegionKeywords)
+			if !ok {
+				logDebugf("事件未匹配地区关键词，跳过通知 - ID: %s, 名称: %s", incident.ID, incident.Name)
+				continue
+			}
+			change.Section += fmt.Sprintf("> 匹配关键词: %s\n\n", keyword)
+		}
+		if !s.nameAllowed(incident) {
+			continue
+		}
+		if len(s.config().WatchComponents) > 0 && !s.incidentWatched(incident) {
+			logDebugf("事件未影响订阅的组件，跳过通知 - ID: %s, 名称: %s", incident.ID, incident.Name)
+			continue
+		}
+		if s.isMuted(incident, change.Event.ChangeType) {
+			continue
+		}
+		if s.isDuplicateAcrossPages(incident, change.Event.ChangeType) {
+			continue
+		}
+		filtered = append(filtered, change)
+	}
+	return filtered
+}
+
+// 事件状态是否在 NOTIFY_STATUSES 中，未配置时全部通知
+func (s *Service) statusNotified(status string) bool {
+	if len(s.config().NotifyStatuses) == 0 {
+		return true
+	}
+	for _, notified := range s.config().NotifyStatuses {
+		if status == notified {
+			return true
+		}
+	}
+	return false
+}
+
+// 配置 NOTIFY_STATUSES 后，事件在未通知的状态下出现、之后才进入通知的状态时，
+// 首次通知按新事件发送，而不是接收方从未见过的事件的"事件更新"
+func (s *Service) promoteFirstNotified(change incidentChange) incidentChange {
+	if len(s.config().NotifyStatuses) == 0 || change.Event.ChangeType != changeTypeUpdate {
+		return change
+	}
+	incident := change.Event.Incident
+	if _, notified := s.threads.LastNotified(incident.ID); notified {
+		return change
+	}
+	logInfof("事件进入 NOTIFY_STATUSES 中的状态，按新事件通知 - ID: %s, 状态: %s", incident.ID, incident.Status)
+	change.Section = s.changeSection("## 新事件\n", "新事件", templateNew, incident, nil)
+	change.Event.ChangeType = changeTypeNew
+	return change
+}
+
+// 按名称黑白名单正则判断事件是否需要通知，黑名单优先
+func (s *Service) nameAllowed(incident Incident) bool {
+	if block := s.config().IncidentNameBlockRegex; block != nil && block.MatchString(incident.Name) {
+		logDebugf("事件名称匹配黑名单正则，跳过通知 - ID: %s, 名称: %s", incident.ID, incident.Name)
+		return false
+	}
+	if allow := s.config().IncidentNameAllowRegex; allow != nil && !allow.MatchString(incident.Name) {
+		logDebugf("事件名称未匹配白名单正则，跳过通知 - ID: %s, 名称: %s", incident.ID, incident.Name)
+		return false
+	}
+	return true
+}
+
+// 判断事件是否处于静音期，调用方需持有锁。解决通知不受静音影响，过期的静音会被清理
+func (s *Service) isMuted(incident Incident, changeType string) bool {
+	until, ok := s.mutedUntil[incident.ID]
+	if !ok {
+		return false
+	}
+	if s.Now().After(until) {
+		logInfof("事件静音已到期 - ID: %s", incident.ID)
+		delete(s.mutedUntil, incident.ID)
+		return false
+	}
+	if changeType == changeTypeResolved {
+		delete(s.mutedUntil, incident.ID)
+		return false
+	}
+	logDebugf("事件处于静音期，跳过通知 - ID: %s, 名称: %s, 静音截止: %s",
+		incident.ID, incident.Name, until.Format("2006-01-02 15:04:05"))
+	return true
+}
+
+// 在事件名称和最新更新内容中查找地区关键词（不区分大小写），返回匹配到的关键词
+func matchRegionKeyword(incident Incident, keywords []string) (string, bool) {
+	text := strings.ToLower(incident.Name)
+	if latest, ok := incident.latestUpdate(); ok {
+		text += "\n" + strings.ToLower(latest.Body)
+	}
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return keyword, true
+		}
+	}
+	return "", false
+}
+
+// 根据变化列表生成变更通知，调用方需持有锁
+func (s *Service) buildChangeNotification(title, heading string, changes []incidentChange) Notification {
+	sections := make([]string, 0, len(changes))
+	events := make([]IncidentEvent, 0, len(changes))
+	atAll := false
+	for _, change := range changes {
+		sections = append(sections, change.Section)
+		events = append(events, change.Event)
+		atAll = atAll || change.Escalated
+	}
+	if s.config().CorrelateIncidents {
+		sections = s.correlatedSections(changes)
+	}
+
+	content := heading +
+		notificationHeader(s.statusVersion, s.formatTime(s.Now())) +
+		strings.Join(sections, "\n") + "\n\n---\n" +
+		s.componentSummaryLine() +
+		s.notificationFooter(changePages(changes))
+
+	return Notification{
+		Kind:    notifyKindChange,
+		Title:   title,
+		Content: content,
+		Events:  events,
+		AtAll:   atAll,
+	}
+}
+
+// 变化检测和通知的回溯窗口起点，创建时间早于该时间的事件不再通知
+func (s *Service) lookbackStart() time.Time {
+	return s.Now().AddDate(0, 0, -3)
+}
+
+// 开启 NOTIFY_OLD_ACTIVE_INCIDENTS 时，创建时间早于回溯窗口的事件是否仍参与变化检测，调用方需持有锁。
+// 未解决的事件（包括首次发现的）照常检测；已解决的事件只在缓存中仍为未解决时检测，以便发送解决通知
+func (s *Service) trackOldIncident(incident Incident) bool {
+	if !s.config().NotifyOldActiveIncidents {
+		return false
+	}
+	if !incident.isResolved() {
+		return true
+	}
+	cached, ok := s.lastIncidents[incident.ID]
+	return ok && !cached.isResolved()
+}
+
+// 事件是否超过缓存保留期限。开启 NOTIFY_OLD_ACTIVE_INCIDENTS 时未解决的事件不过期，
+// 否则较早的未解决事件每轮被清理后又会作为新事件重复通知
+func (s *Service) cacheExpired(incident Incident, cutoff time.Time) bool {
+	if s.config().NotifyOldActiveIncidents && !incident.isResolved() {
+		return false
+	}
+	return incident.CreatedAt.Before(cutoff)
+}
+
+// 判断给定时间是否处于静默时段，支持跨越午夜的时间窗口
+func (s *Service) inQuietHours(now time.Time) bool {
+	start, end := s.config().QuietHoursStart, s.config().QuietHoursEnd
+	if start < 0 || end < 0 {
+		return false
+	}
+	hour := now.Hour()
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+func (s *Service) sendDailyReport(ctx context.Context) {
+	// 计划维护需要额外的网络请求，在持锁生成报告之前获取
+	var maintenances []Maintenance
+	if s.config().ReportIncludeMaintenances {
+		maintenances = s.fetchUpcomingMaintenances(ctx)
+	}
+	report, incidentCount := s.buildDailyReport(maintenances)
+	if incidentCount == 0 && !s.config().SendEmptyDailyReport {
+		logInfof("过去三天没有事件，已关闭空报告，跳过发送每日报告")
+		return
+	}
+
+	logDebugf("准备发送每日报告...")
+	if err := s.notify(ctx, Notification{
+		Kind:    notifyKindDailyReport,
+		Title:   "Cloudflare 每日状态报告",
+		Content: report,
+	}); err != nil {
+		logEvent("error", "notify", logFields{"kind": "daily_report", "error": err.Error()}, "发送每日报告失败: %v", err)
+	} else {
+		logEvent("info", "notify", logFields{"kind": "daily_report"}, "每日报告发送成功")
+	}
+}
+
+// 在读锁保护下生成每日报告内容，各部分由 REPORT_INCLUDE_* 配置控制，同时返回报告涵盖的事件数
+func (s *Service) buildDailyReport(maintenances []Maintenance) (string, int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	logDebugf("开始生成每日报告...")
+
+	var report strings.Builder
+	report.WriteString("# Cloudflare 每日状态报告\n\n")
+	report.WriteString(notificationHeader(s.statusVersion, s.formatTime(s.Now())))
+	report.WriteString(s.formatAvailability(s.Now()))
+
+	threeDaysAgo := s.lookbackStart()
+
+	logDebugf("统计 %s 之后的事件...", threeDaysAgo.Format("2006-01-02 15:04:05"))
+
+	var incidents []Incident
+	for _, incident := range s.lastIncidents {
+		if incident.CreatedAt.After(threeDaysAgo) {
+			incidents = append(incidents, incident)
+		}
+	}
+
+	logDebugf("统计完成，共有 %d 个事件", len(incidents))
+	if s.config().ReportGroupByImpact {
+		// 按影响程度分组时组内按时间倒序，与 impact 排序一致
+		sortReportIncidents(incidents, reportSortImpact)
+	} else {
+		sortReportIncidents(incidents, s.config().ReportSortOrder)
+	}
+
+	if s.config().ReportIncludeStats && len(incidents) > 0 {
+		report.WriteString(s.formatIncidentStats(incidents))
+		report.WriteString(formatStatusDurations(incidents, s.Now()))
+	}
+	if s.config().MonitorComponents {
+		report.WriteString(s.formatDegradedComponents())
+	}
+	if s.config().ReportIncludeMaintenances {
+		report.WriteString(s.formatMaintenances(maintenances))
+	}
+
+	listed := incidents
+	alreadyNotified := 0
+	if s.config().ReportDedupWindowMinutes > 0 && s.config().ReportDedupMode == reportDedupOmit {
+		listed = nil
+		for _, incident := range incidents {
+			if _, ok := s.recentlyNotified(incident); ok {
+				alreadyNotified++
+				continue
+			}
+			listed = append(listed, incident)
+		}
+	}
+	if s.config().ReportMaxIncidents > 0 && len(listed) > s.config().ReportMaxIncidents {
+		listed = listed[:s.config().ReportMaxIncidents]
+	}
+	groups := make([][]Incident, len(listed))
+	for i, incident := range listed {
+		groups[i] = []Incident{incident}
+	}
+	if s.config().CorrelateIncidents {
+		// 按影响程度分组时只关联同一分组内的事件，保持分组标题的顺序
+		var sameGroup func(a, b Incident) bool
+		if s.config().ReportGroupByImpact {
+			sameGroup = func(a, b Incident) bool {
+				return reportImpactHeading(a.Impact) == reportImpactHeading(b.Impact)
+			}
+		}
+		groups = correlateIncidents(listed, s.Now(), sameGroup)
+	}
+
+	if len(listed) > 0 && !s.config().ReportGroupByImpact {
+		if len(groups) < len(listed) {
+			report.WriteString(fmt.Sprintf("## 事件列表（%d 个事件，归为 %d 组）\n\n", len(listed), len(groups)))
+		} else {
+			report.WriteString("## 事件列表\n\n")
+		}
+	}
+	for i, group := range groups {
+		heading := reportImpactHeading(group[0].Impact)
+		if s.config().ReportGroupByImpact && (i == 0 || reportImpactHeading(groups[i-1][0].Impact) != heading) {
+			report.WriteString(fmt.Sprintf("## %s（%d）\n\n", s.reportImpactTitle(group[0].Impact), countImpactHeading(listed, heading)))
+		}
+		if len(group) > 1 {
+			report.WriteString("### " + correlationHeading(group) + "\n\n")
+		}
+		for _, incident := range group {
+			logDebugf("添加事件到报告 - ID: %s, 名称: %s", incident.ID, incident.Name)
+			var updates []Update
+			if s.config().ReportIncludeHistory {
+				updates = incident.IncidentUpdates
+			}
+			if out, ok := s.renderTemplate(templateDaily, incident, updates); ok {
+				report.WriteString(out)
+			} else if len(group) > 1 {
+				// 关联组内的事件作为子条目，标题降低一级
+				report.WriteString(strings.Replace(s.formatReportIncident(incident, updates), "### ", "#### ", 1))
+			} else {
+				report.WriteString(s.formatReportIncident(incident, updates))
+			}
+		}
+	}
+
+	if alreadyNotified > 0 {
+		report.WriteString(fmt.Sprintf("另有 %d 个事件已在最近 %d 分钟内单独通知，未重复列出。\n", alreadyNotified, s.config().ReportDedupWindowMinutes))
+	}
+	if omitted := len(incidents) - alreadyNotified - len(listed); omitted > 0 {
+		report.WriteString(fmt.Sprintf("另有 %d 个事件未列出（REPORT_MAX_INCIDENTS=%d）。\n", omitted, s.config().ReportMaxIncidents))
+	}
+
+	if len(incidents) == 0 {
+		logDebugf("没有发现事件")
+		report.WriteString("## ✅ 系统正常\n\n过去三天没有发生任何事件，监控运行正常。\n")
+	}
+
+	report.WriteString("\n---\n")
+	report.WriteString(s.notificationFooter(nil))
+
+	return report.String(), len(incidents)
+}
+
+// 按影响程度从高到低统计进行中的事件，如 "当前: 1 critical, 2 major, 0 minor 进行中"。
+// critical、major、minor 始终列出，其他影响程度只在有事件时列出
+func (s *Service) activeSeveritySummary(incidents []Incident) string {
+	counts := make(map[string]int)
+	for _, incident := range incidents {
+		if incident.isResolved() {
+			continue
+		}
+		impact := incident.Impact
+		if _, ok := impactRank[impact]; !ok {
+			impact = "none"
+		}
+		counts[impact]++
+	}
+	impacts := make([]string, 0, len(impactRank))
+	for impact := range impactRank {
+		impacts = append(impacts, impact)
 	}
-	if config.MaxIncidents <= 0 {
-		return config, fmt.Errorf("MAX_INCIDENTS 必须大于0")
+	sort.Slice(impacts, func(i, j int) bool {
+		return impactRank[impacts[i]] > impactRank[impacts[j]]
+	})
+
+	var parts []string
+	for _, impact := range impacts {
+		if counts[impact] > 0 || impactRank[impact] >= impactRank["minor"] {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[impact], s.impactLabel(impact)))
+		}
 	}
-	if config.DingtalkWebhookToken == "" {
-		return config, fmt.Errorf("DINGTALK_WEBHOOK_TOKEN 不能为空")
+	return fmt.Sprintf("**当前: %s 进行中**\n\n", strings.Join(parts, ", "))
+}
+
+// 每日报告按影响程度分组时的分组标题
+func reportImpactHeading(impact string) string {
+	switch impact {
+	case "critical":
+		return "Critical 事件"
+	case "major":
+		return "Major 事件"
+	case "minor":
+		return "Minor 事件"
+	case "none", "":
+		return "无影响事件"
+	}
+	return impact + " 事件"
+}
+
+// 每日报告分组标题中展示的名称，配置了 IMPACT_LABELS 时使用对应名称
+func (s *Service) reportImpactTitle(impact string) string {
+	if impact == "" {
+		impact = "none"
 	}
-	if config.DingtalkSecret == "" {
-		return config, fmt.Errorf("DINGTALK_SECRET 不能为空")
+	if label, ok := s.config().ImpactLabels[impact]; ok {
+		return label + " 事件"
 	}
+	return reportImpactHeading(impact)
+}
 
-	return config, nil
+// 统计属于指定分组的事件数量
+func countImpactHeading(incidents []Incident, heading string) int {
+	count := 0
+	for _, incident := range incidents {
+		if reportImpactHeading(incident.Impact) == heading {
+			count++
+		}
+	}
+	return count
 }
 
-func (s *Service) sendDingtalkNotification(title, content string) error {
-	log.Printf("准备发送钉钉通知 - 标题: %s", title)
+// 每日报告中近期已单独通知的事件的处理方式
+const (
+	reportDedupOmit = "omit" // 不再列出，只注明数量
+	reportDedupMark = "mark" // 照常列出，标题注明已通知
+)
 
-	message := DingtalkMessage{
-		Msgtype: "markdown",
-	}
-	message.Markdown.Title = title
-	message.Markdown.Text = content
+// 每日报告的事件排序方式
+const (
+	reportSortTime   = "time"   // 按创建时间倒序
+	reportSortImpact = "impact" // 按影响程度从高到低，相同时按创建时间倒序
+)
 
-	jsonData, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("生成钉钉消息 JSON 失败: %v", err)
-		return err
+// 按配置的方式对每日报告中的事件排序
+func sortReportIncidents(incidents []Incident, order string) {
+	sort.SliceStable(incidents, func(i, j int) bool {
+		if order == reportSortImpact {
+			if ri, rj := impactRank[incidents[i].Impact], impactRank[incidents[j].Impact]; ri != rj {
+				return ri > rj
+			}
+		}
+		if !incidents[i].CreatedAt.Equal(incidents[j].CreatedAt) {
+			return incidents[i].CreatedAt.After(incidents[j].CreatedAt)
+		}
+		return incidents[i].ID < incidents[j].ID
+	})
+}
+
+// 生成每日报告中单个事件的内容，比实时通知更紧凑：一行概要，按需附带更新历史
+func (s *Service) formatReportIncident(incident Incident, updates []Update) string {
+	var entry strings.Builder
+	entry.WriteString(fmt.Sprintf("### %s%s", s.pagePrefix(incident), incident.Name))
+	if notifiedAt, ok := s.recentlyNotified(incident); ok {
+		entry.WriteString(fmt.Sprintf("（已通知，%s）", s.formatTime(notifiedAt)))
+	}
+	entry.WriteString("\n")
+	entry.WriteString(fmt.Sprintf("- %s / %s，创建于 %s", incident.Status, s.impactLabel(incident.Impact),
+		s.formatTime(incident.CreatedAt)))
+	if duration, resolved := incident.resolutionDuration(); resolved {
+		entry.WriteString(fmt.Sprintf("，耗时 %.0f 分钟解决", duration.Minutes()))
+	}
+	entry.WriteString("\n")
+	entry.WriteString(fmt.Sprintf("- 链接: %s\n", s.incidentLink(incident)))
+
+	if len(updates) > 0 {
+		sorted := make([]Update, len(updates))
+		copy(sorted, updates)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		})
+		entry.WriteString("\n更新历史:\n")
+		for _, update := range sorted {
+			entry.WriteString(fmt.Sprintf("- %s [%s]: %s\n",
+				s.formatTime(update.CreatedAt),
+				update.Status,
+				sanitizeUpdateBody(update.Body)))
+		}
 	}
-	log.Printf("钉钉消息 JSON 生成成功，长度: %d 字节", len(jsonData))
 
-	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
-	sign := s.generateDingtalkSign(timestamp)
-	log.Printf("生成钉钉签名成功，时间戳: %s", timestamp)
+	entry.WriteString("\n")
+	return entry.String()
+}
 
-	url := fmt.Sprintf("https://oapi.dingtalk.com/robot/send?access_token=%s&timestamp=%s&sign=%s",
-		s.config.DingtalkWebhookToken, timestamp, sign)
+// 可用性统计窗口
+const availabilityWindow = 24 * time.Hour
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("发送钉钉 HTTP 请求失败: %v", err)
-		return err
+// 生成每日报告开头的可用性摘要，调用方需持有锁。影响程度在 SLA_IMPACT_LEVELS 中的事件
+// 从创建到解决（未解决时到 now）的时间视为不可用，重叠的事件只计算一次
+func (s *Service) formatAvailability(now time.Time) string {
+	levels := make(map[string]bool, len(s.config().SLAImpactLevels))
+	for _, level := range s.config().SLAImpactLevels {
+		levels[level] = true
 	}
-	defer resp.Body.Close()
+	windowStart := now.Add(-availabilityWindow)
 
-	// 读取响应内容
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("读取钉钉响应失败: %v", err)
-		return err
+	type interval struct{ start, end time.Time }
+	var outages []interval
+	for _, incident := range s.lastIncidents {
+		if !levels[incident.Impact] || incident.CreatedAt.IsZero() {
+			continue
+		}
+		start, end := incident.CreatedAt, now
+		if resolvedAt := incident.resolvedTime(); !resolvedAt.IsZero() {
+			end = resolvedAt
+		}
+		if start.Before(windowStart) {
+			start = windowStart
+		}
+		if end.After(now) {
+			end = now
+		}
+		if end.After(start) {
+			outages = append(outages, interval{start, end})
+		}
 	}
-	log.Printf("钉钉响应: HTTP状态码=%d, 响应内容=%s", resp.StatusCode, string(respBody))
 
-	return nil
-}
+	sort.Slice(outages, func(i, j int) bool {
+		return outages[i].start.Before(outages[j].start)
+	})
+	var downtime time.Duration
+	var coveredUntil time.Time
+	for _, outage := range outages {
+		if outage.start.Before(coveredUntil) {
+			outage.start = coveredUntil
+		}
+		if outage.end.After(outage.start) {
+			downtime += outage.end.Sub(outage.start)
+			coveredUntil = outage.end
+		}
+	}
 
-func (s *Service) generateDingtalkSign(timestamp string) string {
-	stringToSign := timestamp + "\n" + s.config.DingtalkSecret
-	h := hmac.New(sha256.New, []byte(s.config.DingtalkSecret))
-	h.Write([]byte(stringToSign))
-	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+	availability := 100 * (1 - float64(downtime)/float64(availabilityWindow))
+	return fmt.Sprintf("**过去24小时可用性: %.2f%%**（按 %s 级别事件计算，不可用 %.0f 分钟）\n\n",
+		availability, strings.Join(s.config().SLAImpactLevels, "/"), downtime.Minutes())
 }
 
-func (s *Service) fetchAndProcessIncidents() error {
-	log.Printf("开始获取 Cloudflare 状态数据...")
+// 生成每日报告的统计摘要
+func (s *Service) formatIncidentStats(incidents []Incident) string {
+	impactCounts := make(map[string]int)
+	var resolvedCount, ongoingCount int
+	var totalOutage time.Duration
 
-	resp, err := http.Get("https://www.cloudflarestatus.com/api/v2/incidents.json")
-	if err != nil {
-		log.Printf("HTTP 请求失败: %v", err)
-		return err
-	}
-	defer resp.Body.Close()
-	log.Printf("成功获取 HTTP 响应，状态码: %d", resp.StatusCode)
+	for _, incident := range incidents {
+		impact := incident.Impact
+		if impact == "" {
+			impact = "none"
+		}
+		impactCounts[impact]++
 
-	// 获取并保存版本信息
-	if version := resp.Header.Get("X-Statuspage-Version"); version != "" {
-		s.mutex.Lock()
-		s.statusVersion = version
-		s.mutex.Unlock()
-		log.Printf("获取到新的 X-Statuspage-Version: %s", version)
+		duration, resolved := incident.resolutionDuration()
+		if !resolved {
+			ongoingCount++
+			continue
+		}
+		resolvedCount++
+		totalOutage += duration
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("读取响应内容失败: %v", err)
-		return err
+	var stats strings.Builder
+	stats.WriteString("## 统计摘要\n\n")
+	stats.WriteString(fmt.Sprintf("- 事件总数: %d\n", len(incidents)))
+	stats.WriteString(fmt.Sprintf("- 已解决: %d\n", resolvedCount))
+	stats.WriteString(fmt.Sprintf("- 进行中: %d\n", ongoingCount))
+
+	if len(impactCounts) > 0 {
+		impacts := make([]string, 0, len(impactCounts))
+		for impact := range impactCounts {
+			impacts = append(impacts, impact)
+		}
+		sort.Strings(impacts)
+
+		stats.WriteString("- 影响程度分布:")
+		for _, impact := range impacts {
+			stats.WriteString(fmt.Sprintf(" %s=%d", s.impactLabel(impact), impactCounts[impact]))
+		}
+		stats.WriteString("\n")
 	}
-	log.Printf("成功读取响应内容，数据长度: %d 字节", len(body))
 
-	var response Response
-	if err := json.Unmarshal(body, &response); err != nil {
-		log.Printf("JSON 解析失败: %v", err)
-		return err
+	stats.WriteString(fmt.Sprintf("- 累计中断时长: %.0f 分钟\n", totalOutage.Minutes()))
+	if resolvedCount > 0 {
+		mttr := totalOutage / time.Duration(resolvedCount)
+		stats.WriteString(fmt.Sprintf("- 平均解决时间: %.1f 分钟\n", mttr.Minutes()))
 	}
-	log.Printf("成功解析 JSON 数据，获取到 %d 个事件", len(response.Incidents))
 
-	// 按时间排序
-	sort.Slice(response.Incidents, func(i, j int) bool {
-		return response.Incidents[i].CreatedAt.After(response.Incidents[j].CreatedAt)
+	stats.WriteString("\n")
+	return stats.String()
+}
+
+// 计算事件在各状态停留的时长：每条更新的状态持续到下一条更新，
+// 最后一条更新若未解决则持续到 now，已解决的终态不计时
+func statusDurations(incident Incident, now time.Time) map[string]time.Duration {
+	updates := make([]Update, len(incident.IncidentUpdates))
+	copy(updates, incident.IncidentUpdates)
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].CreatedAt.Before(updates[j].CreatedAt)
 	})
-	log.Printf("事件按时间排序完成")
 
-	// 检查变化并发送通知
-	s.checkForChanges(response.Incidents)
-	return nil
+	durations := make(map[string]time.Duration)
+	for i, update := range updates {
+		var end time.Time
+		if i+1 < len(updates) {
+			end = updates[i+1].CreatedAt
+		} else if update.Status != "resolved" && update.Status != "postmortem" {
+			end = now
+		} else {
+			continue
+		}
+		if d := end.Sub(update.CreatedAt); d > 0 {
+			durations[update.Status] += d
+		}
+	}
+	return durations
 }
 
-func (s *Service) formatIncidentDetails(incident Incident) string {
-	var details strings.Builder
-	details.WriteString(fmt.Sprintf("### 事件: %s\n", incident.Name))
-	details.WriteString(fmt.Sprintf("- ID: %s\n", incident.ID))
-	details.WriteString(fmt.Sprintf("- 状态: %s\n", incident.Status))
-	details.WriteString(fmt.Sprintf("- 影响程度: %s\n", incident.Impact))
-	details.WriteString(fmt.Sprintf("- 创建时间: %s\n", incident.CreatedAt.Format("2006-01-02 15:04:05")))
-	details.WriteString(fmt.Sprintf("- 更新时间: %s\n", incident.UpdatedAt.Format("2006-01-02 15:04:05")))
-
-	if !incident.MonitoringAt.IsZero() {
-		details.WriteString(fmt.Sprintf("- 监控开始时间: %s\n", incident.MonitoringAt.Format("2006-01-02 15:04:05")))
+// 生成各状态累计停留时长的表格
+func formatStatusDurations(incidents []Incident, now time.Time) string {
+	totals := make(map[string]time.Duration)
+	for _, incident := range incidents {
+		for status, d := range statusDurations(incident, now) {
+			totals[status] += d
+		}
 	}
-	if !incident.ResolvedAt.IsZero() {
-		details.WriteString(fmt.Sprintf("- 解决时间: %s\n", incident.ResolvedAt.Format("2006-01-02 15:04:05")))
+	if len(totals) == 0 {
+		return ""
 	}
 
-	if len(incident.IncidentUpdates) > 0 {
-		details.WriteString("\n更新历史:\n")
-		for _, update := range incident.IncidentUpdates {
-			details.WriteString(fmt.Sprintf("- %s [%s]: %s\n",
-				update.CreatedAt.Format("2006-01-02 15:04:05"),
-				update.Status,
-				update.Body))
+	order := []string{"investigating", "identified", "monitoring"}
+	known := map[string]bool{"investigating": true, "identified": true, "monitoring": true}
+	var others []string
+	for status := range totals {
+		if !known[status] {
+			others = append(others, status)
 		}
 	}
-
-	if incident.Shortlink != "" {
-		details.WriteString(fmt.Sprintf("\n事件链接: %s\n", incident.Shortlink))
+	sort.Strings(others)
+
+	var table strings.Builder
+	table.WriteString("## 状态停留时长\n\n")
+	table.WriteString("| 状态 | 累计时长 |\n")
+	table.WriteString("| --- | --- |\n")
+	for _, status := range append(order, others...) {
+		if d, ok := totals[status]; ok {
+			table.WriteString(fmt.Sprintf("| %s | %.0f 分钟 |\n", status, d.Minutes()))
+		}
 	}
-
-	details.WriteString("\n")
-	return details.String()
+	table.WriteString("\n")
+	return table.String()
 }
 
-func (s *Service) formatNotificationHeader() string {
+func (s *Service) shouldSendDailyReport() bool {
+	now := s.Now().UTC()
 	s.mutex.RLock()
-	version := s.statusVersion
+	lastReportTime := s.lastReportTime
 	s.mutex.RUnlock()
+	lastReport := lastReportTime.UTC()
+
+	// 当前是否为配置的发送时间之一
+	due := false
+	for _, hour := range s.config().DailyReportHours {
+		if now.Hour() == hour {
+			due = true
+			break
+		}
+	}
+	if !due {
+		return false
+	}
+	// 每个发送时间每天最多发送一次：上次发送不在同一天的同一小时即可发送。
+	// 按完整日期比较，避免只比较日号时跨月的同一日号被误判为同一天
+	return lastReportTime.IsZero() || !lastReport.Truncate(time.Hour).Equal(now.Truncate(time.Hour))
+}
 
-	var header strings.Builder
-	header.WriteString(fmt.Sprintf("时间: %s\n\n", time.Now().Format("2006-01-02 15:04:05")))
-	if version != "" {
-		header.WriteString(fmt.Sprintf("X-Statuspage-Version: %s\n", version))
+// 执行一轮完整的检查：获取并处理事件，到达每日报告时间时发送报告。主循环和 -once 模式共用，
+// 只依赖注入的时钟（s.Now）和配置中的地址，可以直接驱动完整的处理流程。
+// 获取数据失败时返回获取数据的错误；reportOnFetchError 为 true 时（主循环）仍按时发送基于缓存的每日报告，
+// 为 false 时（-once 模式）不发送，与单次运行获取失败即退出的行为保持一致
+func (s *Service) runTick(ctx context.Context, reportOnFetchError bool) error {
+	_, err := s.fetchAndProcessIncidents(ctx)
+	if err != nil && !reportOnFetchError {
+		return err
 	}
-	header.WriteString("\n")
-	return header.String()
+	if s.shouldSendDailyReport() {
+		logInfof("触发每日报告发送...")
+		s.sendDailyReport(ctx)
+		s.markReportSent(s.Now())
+		logDebugf("每日报告处理完成")
+	}
+	return err
 }
 
-func (s *Service) checkForChanges(incidents []Incident) {
+// 记录每日报告的发送时间，配置了 STATE_FILE 时立即写入状态文件，
+// 避免报告发送后、下一轮检查保存状态前重启导致同一发送时间重复发送
+func (s *Service) markReportSent(t time.Time) {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	s.lastReportTime = t
+	s.mutex.Unlock()
+	if s.config().StateFile != "" {
+		if err := s.saveState(); err != nil {
+			logErrorf("保存状态失败: %v", err)
+		}
+	}
+}
+
+// 构建信息，发布时通过 -ldflags "-X main.version=... -X main.commit=... -X main.date=..." 注入
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
 
-	log.Printf("开始检查事件变化...")
+func main() {
+	// 配置日志格式。启动阶段的错误通过 logFatalf 以退出码 1 结束进程，
+	// 便于 systemd 等进程管理器识别失败；运行期间单轮检查的错误只记录日志
+	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
+
+	// -once 模式失败时的退出码。最先注册的 defer 最后执行，
+	// 在其他 defer 关闭日志文件和历史存储之后才调用 os.Exit
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	configPath := flag.String("c", "env.config", "配置文件路径，- 表示从标准输入读取，也可以是 http(s):// 地址")
+	once := flag.Bool("once", false, "只执行一次检查后退出，适用于 cron 部署")
+	validate := flag.Bool("validate", false, "只校验配置并输出生效的配置（密钥已脱敏），不发起任何网络请求")
+	backfill := flag.Bool("backfill", false, "将状态页的历史事件回填到 DB_PATH 历史存储后退出，不发送通知")
+	showVersion := flag.Bool("version", false, "输出版本和构建信息后退出")
+	replayDir := flag.String("replay", "", "按顺序回放目录中的事件快照（如 DEBUG_DUMP_DIR 的转储），将通知输出到标准输出后退出")
+	exportCSV := flag.String("export-csv", "", "将近三天内的事件导出为 CSV 文件后退出，- 表示输出到标准输出")
+	flag.Parse()
 
-	// 限制事件数量为配置的最大值
-	if len(incidents) > s.config.MaxIncidents {
-		log.Printf("事件数量超过配置的最大值 %d，将只处理最近的 %d 个事件",
-			s.config.MaxIncidents, s.config.MaxIncidents)
-		incidents = incidents[:s.config.MaxIncidents]
+	if *showVersion {
+		fmt.Printf("cf-status %s\ncommit: %s\nbuilt: %s\ngo: %s\n", version, commit, date, runtime.Version())
+		return
 	}
-	log.Printf("当前处理的事件数量: %d", len(incidents))
 
-	// 第一次运行时初始化并发送通知
-	if s.lastIncidents == nil {
-		log.Printf("首次运行，初始化事件缓存...")
-		s.lastIncidents = make(map[string]Incident)
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		logFatalf("加载配置失败: %v", err)
+	}
+	// 加载配置后立即初始化日志，之后的日志（包括启动信息）都按 LOG_FORMAT 输出到 LOG_FILE。
+	// -validate 模式只输出到标准错误，不创建日志文件
+	var output io.Writer = os.Stderr
+	if config.LogFile != "" && !*validate {
+		logFile, err := openRotatingFile(config.LogFile, config.LogMaxSizeMB)
+		if err != nil {
+			logFatalf("初始化日志文件失败: %v", err)
+		}
+		defer logFile.Close()
+		output = logFile
+	}
+	setupLogging(config.LogFormat, config.LogLevel, output)
+	if *validate {
+		validateConfig(config)
+		return
+	}
+	logInfof("服务启动... 版本: %s, 提交: %s, 构建时间: %s", version, commit, date)
+	logDebugf("已加载配置文件: %s", *configPath)
+	if config.LogFile != "" {
+		logInfof("日志将写入文件: %s", config.LogFile)
+	}
+	reportHours := make([]string, len(config.DailyReportHours))
+	for i, hour := range config.DailyReportHours {
+		reportHours[i] = fmt.Sprintf("%d:00", hour)
+	}
+	logInfof("配置加载成功，检查间隔: %d 分钟，每日报告时间: UTC %s，最大事件数量: %d，通知渠道: %s",
+		config.CheckIntervalMinutes, strings.Join(reportHours, ","), config.MaxIncidents, strings.Join(config.Notifiers, ","))
 
-		var firstRunNotification strings.Builder
-		firstRunNotification.WriteString("# Cloudflare 状态监控启动\n\n")
-		firstRunNotification.WriteString(fmt.Sprintf("时间: %s\n", time.Now().Format("2006-01-02 15:04:05")))
-		if s.statusVersion != "" {
-			firstRunNotification.WriteString(fmt.Sprintf("X-Statuspage-Version: %s\n", s.statusVersion))
+	service := &Service{
+		Now:       time.Now,
+		threads:   newThreadStore(),
+		approvals: newApprovalQueue(),
+
+		configPath: *configPath,
+		reloaded:   make(chan struct{}, 1),
+	}
+	rt, err := newRuntimeConfig(config)
+	if err != nil {
+		logFatalf("加载配置失败: %v", err)
+	}
+	// 回放模式只把通知输出到标准输出，不初始化真实的通知渠道
+	if *replayDir == "" && *exportCSV == "" {
+		notifiers, err := buildNotifiers(service, rt)
+		if err != nil {
+			logFatalf("初始化通知渠道失败: %v", err)
 		}
-		firstRunNotification.WriteString("\n")
+		rt.notifiers = notifiers
+	}
 
-		if len(incidents) > 0 {
-			firstRunNotification.WriteString("## 当前活跃事件\n\n")
-			for _, incident := range incidents {
-				log.Printf("处理初始事件 - ID: %s, 名称: %s, 状态: %s",
-					incident.ID, incident.Name, incident.Status)
-				s.lastIncidents[incident.ID] = incident
-				firstRunNotification.WriteString(s.formatIncidentDetails(incident))
-			}
-		} else {
-			log.Printf("初始化时没有发现活跃事件")
-			firstRunNotification.WriteString("当前没有活跃的事件。\n")
+	if config.TemplateFile != "" {
+		tmpl, err := loadTemplates(config.TemplateFile, config.TimeFormat, config.ImpactLabels)
+		if err != nil {
+			logFatalf("加载通知模板失败: %v", err)
 		}
+		rt.templates = tmpl
+		logInfof("已加载通知模板: %s", config.TemplateFile)
+	}
+	service.current.Store(rt)
 
-		firstRunNotification.WriteString("\n---\n")
-		firstRunNotification.WriteString("详细状态请访问: https://www.cloudflarestatus.com/")
+	if *replayDir != "" {
+		if err := service.runReplay(*replayDir, os.Stdout); err != nil {
+			logFatalf("回放失败: %v", err)
+		}
+		return
+	}
 
-		log.Printf("事件缓存初始化完成，共缓存 %d 个事件", len(s.lastIncidents))
+	if config.DBPath != "" {
+		history, err := openHistoryStore(config.DBPath)
+		if err != nil {
+			logFatalf("打开事件历史存储失败: %v", err)
+		}
+		defer history.Close()
+		service.history = history
+		logInfof("事件历史存储已启用: %s", config.DBPath)
+	}
 
-		// 发送首次运行通知
-		if err := s.sendDingtalkNotification("Cloudflare 状态监控已启动", firstRunNotification.String()); err != nil {
-			log.Printf("发送首次运行通知失败: %v", err)
-		} else {
-			log.Printf("首次运行通知发送成功")
+	if *backfill {
+		if err := service.runBackfill(context.Background()); err != nil {
+			logFatalf("回填历史事件失败: %v", err)
 		}
 		return
 	}
 
-	var changes []string
-	threeDaysAgo := time.Now().AddDate(0, 0, -3)
-	log.Printf("设置时间范围：%s 之后的事件", threeDaysAgo.Format("2006-01-02 15:04:05"))
+	if config.StateFile != "" {
+		if err := service.loadState(); err != nil {
+			logFatalf("加载状态失败: %v", err)
+		}
+	}
 
-	// 检查新事件和更新
-	for _, incident := range incidents {
-		if !incident.CreatedAt.After(threeDaysAgo) {
-			log.Printf("跳过较早的事件 - ID: %s, 创建时间: %s",
-				incident.ID, incident.CreatedAt.Format("2006-01-02 15:04:05"))
-			continue
+	if *exportCSV != "" {
+		if err := service.runExportCSV(*exportCSV); err != nil {
+			logFatalf("导出 CSV 失败: %v", err)
 		}
+		return
+	}
 
-		oldIncident, exists := s.lastIncidents[incident.ID]
-		if !exists {
-			log.Printf("发现新事件 - ID: %s, 名称: %s", incident.ID, incident.Name)
-			changes = append(changes, fmt.Sprintf("## 新事件\n%s", s.formatIncidentDetails(incident)))
-		} else if oldIncident.UpdatedAt != incident.UpdatedAt {
-			log.Printf("事件更新 - ID: %s, 名称: %s, 新状态: %s",
-				incident.ID, incident.Name, incident.Status)
+	if config.ProbeStatusPages {
+		ctx, cancel := service.tickContext()
+		service.probeStatusPages(ctx)
+		cancel()
+	}
 
-			// 记录状态变化
-			if oldIncident.Status != incident.Status {
-				log.Printf("状态变化 - ID: %s, 旧状态: %s, 新状态: %s",
-					incident.ID, oldIncident.Status, incident.Status)
-			}
+	if len(config.WatchComponents) > 0 {
+		ctx, cancel := service.tickContext()
+		service.logWatchableComponents(ctx)
+		cancel()
+	}
 
-			changes = append(changes, fmt.Sprintf("## 事件更新\n%s", s.formatIncidentDetails(incident)))
-		} else {
-			log.Printf("事件无变化 - ID: %s, 名称: %s", incident.ID, incident.Name)
-		}
-		s.lastIncidents[incident.ID] = incident
+	if config.CatchupOnStartup {
+		ctx, cancel := service.tickContext()
+		service.catchUp(ctx)
+		cancel()
 	}
 
-	// 清理超过最大数量的旧事件
-	if len(s.lastIncidents) > s.config.MaxIncidents {
-		log.Printf("清理旧事件，当前缓存数量: %d，最大允许数量: %d",
-			len(s.lastIncidents), s.config.MaxIncidents)
-		var incidentSlice []Incident
-		for _, incident := range s.lastIncidents {
-			incidentSlice = append(incidentSlice, incident)
+	if *once {
+		if config.WarmupChecks > 0 {
+			logInfof("-once 模式不进行 WARMUP_CHECKS 预热检查")
 		}
-		sort.Slice(incidentSlice, func(i, j int) bool {
-			return incidentSlice[i].CreatedAt.After(incidentSlice[j].CreatedAt)
-		})
-		newIncidents := make(map[string]Incident)
-		for i := 0; i < s.config.MaxIncidents && i < len(incidentSlice); i++ {
-			newIncidents[incidentSlice[i].ID] = incidentSlice[i]
-			log.Printf("保留事件 - ID: %s, 名称: %s",
-				incidentSlice[i].ID, incidentSlice[i].Name)
+		if err := runOnce(service); err != nil {
+			logErrorf("单次运行失败: %v", err)
+			exitCode = 1
 		}
-		s.lastIncidents = newIncidents
-		log.Printf("清理完成，现有缓存数量: %d", len(s.lastIncidents))
+		return
 	}
 
-	log.Printf("事件检查完成，发现 %d 个变化", len(changes))
-
-	// 如果有变化，发送通知
-	if len(changes) > 0 {
-		log.Printf("准备发送钉钉通知...")
-		notification := "# Cloudflare 状态更新\n\n" +
-			s.formatNotificationHeader() +
-			strings.Join(changes, "\n") + "\n\n---\n" +
-			"详细状态请访问: https://www.cloudflarestatus.com/"
+	// 没有可恢复的事件缓存（冷启动）时，先进行 WARMUP_CHECKS 次只填充缓存的检查
+	service.mutex.RLock()
+	coldStart := service.lastIncidents == nil
+	service.mutex.RUnlock()
+	if coldStart && config.WarmupChecks > 0 {
+		service.warmupRemaining = config.WarmupChecks
+		logInfof("冷启动，将先进行 %d 次预热检查，期间只填充事件缓存、不发送通知", config.WarmupChecks)
+	}
 
-		if err := s.sendDingtalkNotification("Cloudflare 状态更新", notification); err != nil {
-			log.Printf("发送钉钉通知失败: %v", err)
-		} else {
-			log.Printf("钉钉通知发送成功")
+	if config.HealthListenAddr != "" {
+		if err := service.startHTTPServer(config.HealthListenAddr); err != nil {
+			logFatalf("启动 HTTP 服务失败: %v", err)
 		}
-	} else {
-		log.Printf("没有发现变化，跳过通知")
 	}
-}
-
-func (s *Service) sendDailyReport() {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
 
-	log.Printf("开始生成每日报告...")
+	// 首次运行
+	logDebugf("执行首次数据获取...")
+	ctx, cancel := service.tickContext()
+	if _, err := service.fetchAndProcessIncidents(ctx); err != nil {
+		logErrorf("初始化数据获取失败: %v", err)
+	} else {
+		logDebugf("首次数据获取成功")
+	}
+	cancel()
 
-	var report strings.Builder
-	report.WriteString("# Cloudflare 每日状态报告\n\n")
-	report.WriteString(s.formatNotificationHeader())
+	// 每轮检查后按当前间隔和随机偏移重新设置定时器，而不是使用固定间隔的 Ticker
+	service.adjustPollInterval()
+	timer := time.NewTimer(service.nextTickDelay())
+	defer timer.Stop()
 
-	threeDaysAgo := time.Now().AddDate(0, 0, -3)
-	hasIncidents := false
-	incidentCount := 0
+	// 收到 SIGHUP 时重新加载配置，不影响事件缓存
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 
-	log.Printf("统计 %s 之后的事件...", threeDaysAgo.Format("2006-01-02 15:04:05"))
+	logDebugf("进入主循环，等待定时触发...")
 
-	for _, incident := range s.lastIncidents {
-		if incident.CreatedAt.After(threeDaysAgo) {
-			hasIncidents = true
-			incidentCount++
-			log.Printf("添加事件到报告 - ID: %s, 名称: %s", incident.ID, incident.Name)
-			report.WriteString(s.formatIncidentDetails(incident))
+	for {
+		select {
+		case <-reload:
+			logInfof("收到 SIGHUP，重新加载配置文件: %s", *configPath)
+			if _, err := service.reloadConfig(*configPath); err != nil {
+				logErrorf("重新加载配置失败，继续使用原配置: %v", err)
+				continue
+			}
+			service.adjustPollInterval()
+			resetTimer(timer, service.nextTickDelay())
+			logInfof("配置已重新加载，检查间隔: %v，通知渠道: %s",
+				service.currentPollInterval(), strings.Join(service.config().Notifiers, ","))
+		case <-service.reloaded:
+			service.adjustPollInterval()
+			resetTimer(timer, service.nextTickDelay())
+		case <-timer.C:
+			logDebugf("定时器触发，开始新一轮检查...")
+			service.recordHeartbeat(service.Now())
+			ctx, cancel := service.tickContext()
+			if err := service.runTick(ctx, true); err != nil {
+				logEvent("error", "scheduler", logFields{"error": err.Error()}, "获取数据失败: %v", err)
+			} else {
+				logEvent("info", "scheduler", nil, "本轮检查完成")
+			}
+			cancel()
+			service.adjustPollInterval()
+			timer.Reset(service.nextTickDelay())
 		}
 	}
+}
+
+// 为一轮检查创建上下文，超时时间为检查间隔，避免慢请求拖进下一轮
+func (s *Service) tickContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.currentPollInterval())
+}
 
-	log.Printf("统计完成，共有 %d 个事件", incidentCount)
+// 当前生效的检查间隔
+func (s *Service) currentPollInterval() time.Duration {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.pollInterval > 0 {
+		return s.pollInterval
+	}
+	return time.Duration(s.config().CheckIntervalMinutes) * time.Minute
+}
 
-	if !hasIncidents {
-		log.Printf("没有发现事件")
-		report.WriteString("过去三天没有发生任何事件。\n")
+// 根据缓存中是否有未解决事件计算下一轮的检查间隔，需要切换时返回新间隔和 true。
+// 切换到活跃间隔立即生效；恢复正常间隔前需在活跃间隔下至少运行一个正常间隔，避免频繁重置定时器
+func (s *Service) nextPollInterval(now time.Time) (time.Duration, bool) {
+	normal := time.Duration(s.config().CheckIntervalMinutes) * time.Minute
+	if s.config().ActiveCheckIntervalMinutes <= 0 {
+		return normal, false
 	}
 
-	report.WriteString("\n---\n")
-	report.WriteString("详细状态请访问: https://www.cloudflarestatus.com/")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	desired := normal
+	for _, incident := range s.lastIncidents {
+		if !incident.isResolved() {
+			desired = time.Duration(s.config().ActiveCheckIntervalMinutes) * time.Minute
+			break
+		}
+	}
+	current := s.pollInterval
+	if current == 0 {
+		current = normal
+	}
+	if desired == current {
+		return current, false
+	}
+	if desired > current && now.Sub(s.pollIntervalChangedAt) < normal {
+		return current, false
+	}
+	s.pollInterval = desired
+	s.pollIntervalChangedAt = now
+	return desired, true
+}
 
-	log.Printf("准备发送每日报告...")
-	if err := s.sendDingtalkNotification("Cloudflare 每日状态报告", report.String()); err != nil {
-		log.Printf("发送每日报告失败: %v", err)
+// 检查后按需切换检查间隔，新间隔在下次设置定时器时生效
+func (s *Service) adjustPollInterval() {
+	interval, changed := s.nextPollInterval(s.Now())
+	if !changed {
+		return
+	}
+	if interval < time.Duration(s.config().CheckIntervalMinutes)*time.Minute {
+		logEvent("info", "scheduler", logFields{"interval_seconds": interval.Seconds()},
+			"存在未解决的事件，检查间隔缩短为 %v", interval)
 	} else {
-		log.Printf("每日报告发送成功")
+		logEvent("info", "scheduler", logFields{"interval_seconds": interval.Seconds()},
+			"所有事件已解决，检查间隔恢复为 %v", interval)
 	}
 }
 
-func (s *Service) shouldSendDailyReport() bool {
-	now := time.Now().UTC()
-	lastReport := s.lastReportTime.UTC()
+// 下一轮检查前的等待时间：当前检查间隔加上 0 到 POLL_JITTER_SECONDS 秒的随机偏移，避免多个实例同时请求。
+// 检查间隔不超过一小时时，加上偏移后也不超过一小时，保证每个整点小时内至少检查一次，每日报告不会错过发送时间
+func (s *Service) nextTickDelay() time.Duration {
+	interval := s.currentPollInterval()
+	if s.config().PollJitterSeconds <= 0 {
+		return interval
+	}
+	jitter := time.Duration(rand.Int63n(int64(s.config().PollJitterSeconds)*int64(time.Second) + 1))
+	if interval <= time.Hour && interval+jitter > time.Hour {
+		jitter = time.Hour - interval
+	}
+	logDebugf("下一轮检查间隔 %v，随机偏移 %v", interval, jitter.Round(time.Millisecond))
+	return interval + jitter
+}
 
-	// 如果从未发送过报告，或者上次发送是在不同的日期
-	if s.lastReportTime.IsZero() || now.Day() != lastReport.Day() {
-		// 检查当前是否到达配置的发送时间
-		if now.Hour() == s.config.DailyReportUTCHour {
-			return true
+// 重新设置尚未触发的定时器，已触发但未读取的事件先丢弃
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
 		}
 	}
-	return false
+	timer.Reset(d)
 }
 
-func main() {
-	// 配置日志格式
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
-	log.Printf("服务启动...")
+// 允许的实际触发间隔与配置间隔的最大偏差比例
+const tickDriftThreshold = 0.2
 
-	configPath := flag.String("c", "env.config", "配置文件路径")
-	flag.Parse()
+// 记录心跳时间，实际触发间隔偏离配置间隔过多时输出警告
+func (s *Service) recordHeartbeat(now time.Time) {
+	s.mutex.Lock()
+	last := s.lastHeartbeat
+	s.lastHeartbeat = now
+	s.mutex.Unlock()
 
-	log.Printf("加载配置文件: %s", *configPath)
-	config, err := loadConfig(*configPath)
-	if err != nil {
-		log.Printf("加载配置失败: %v", err)
+	if last.IsZero() {
 		return
 	}
-	log.Printf("配置加载成功，检查间隔: %d 分钟，每日报告时间: UTC %d:00，最大事件数量: %d",
-		config.CheckIntervalMinutes, config.DailyReportUTCHour, config.MaxIncidents)
-
-	service := &Service{
-		config: config,
+	expected := s.currentPollInterval()
+	actual := now.Sub(last)
+	// 随机偏移造成的延后不计入偏差
+	measured := actual
+	if jitter := time.Duration(s.config().PollJitterSeconds) * time.Second; measured > expected {
+		if measured-expected <= jitter {
+			measured = expected
+		} else {
+			measured -= jitter
+		}
 	}
-
-	// 首次运行
-	log.Printf("执行首次数据获取...")
-	if err := service.fetchAndProcessIncidents(); err != nil {
-		log.Printf("初始化数据获取失败: %v", err)
+	drift := float64(measured-expected) / float64(expected)
+	if drift < 0 {
+		drift = -drift
+	}
+	if drift > tickDriftThreshold {
+		logEvent("warn", "scheduler", logFields{"expected_seconds": expected.Seconds(), "actual_seconds": actual.Seconds()},
+			"定时器触发间隔异常：期望 %v，实际 %v，偏差 %.0f%%", expected, actual.Round(time.Second), drift*100)
 	} else {
-		log.Printf("首次数据获取成功")
+		logDebugf("心跳正常，距上次触发 %v", actual.Round(time.Second))
 	}
+}
 
-	ticker := time.NewTicker(time.Duration(config.CheckIntervalMinutes) * time.Minute)
-	defer ticker.Stop()
-
-	log.Printf("进入主循环，等待定时触发...")
-
-	for {
-		select {
-		case <-ticker.C:
-			log.Printf("定时器触发，开始新一轮检查...")
-			if err := service.fetchAndProcessIncidents(); err != nil {
-				log.Printf("获取数据失败: %v", err)
-			} else {
-				log.Printf("本轮检查完成")
-			}
+// 单次运行模式：执行一次检查，必要时发送每日报告。失败时返回错误，由 main 在关闭资源后以退出码 1 退出
+func runOnce(service *Service) error {
+	if service.config().StateFile == "" {
+		return fmt.Errorf("-once 模式需要配置 STATE_FILE 以在多次运行之间保存事件缓存")
+	}
 
-			if service.shouldSendDailyReport() {
-				log.Printf("触发每日报告发送...")
-				service.sendDailyReport()
-				service.lastReportTime = time.Now()
-				log.Printf("每日报告处理完成")
-			}
-		}
+	logInfof("单次运行模式，开始检查...")
+	ctx, cancel := service.tickContext()
+	defer cancel()
+	if err := service.runTick(ctx, false); err != nil {
+		return fmt.Errorf("获取数据失败: %w", err)
 	}
+	logInfof("单次检查完成，退出")
+	return nil
 }
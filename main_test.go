@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// 只包含给定配置的服务，用于测试只依赖配置的判断逻辑
+func serviceWithConfig(config Config) *Service {
+	s := &Service{}
+	s.current.Store(&runtimeConfig{config: config})
+	return s
+}
+
+func TestInQuietHours(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end int
+		hour       int
+		want       bool
+	}{
+		{name: "未启用", start: -1, end: -1, hour: 3, want: false},
+		{name: "同一天内开始前", start: 1, end: 5, hour: 0, want: false},
+		{name: "同一天内开始小时", start: 1, end: 5, hour: 1, want: true},
+		{name: "同一天内结束前一小时", start: 1, end: 5, hour: 4, want: true},
+		{name: "同一天内结束小时", start: 1, end: 5, hour: 5, want: false},
+		{name: "跨午夜开始前", start: 22, end: 6, hour: 21, want: false},
+		{name: "跨午夜开始小时", start: 22, end: 6, hour: 22, want: true},
+		{name: "跨午夜 23 点", start: 22, end: 6, hour: 23, want: true},
+		{name: "跨午夜 0 点", start: 22, end: 6, hour: 0, want: true},
+		{name: "跨午夜结束前一小时", start: 22, end: 6, hour: 5, want: true},
+		{name: "跨午夜结束小时", start: 22, end: 6, hour: 6, want: false},
+		{name: "跨午夜白天", start: 22, end: 6, hour: 12, want: false},
+		{name: "结束于 0 点", start: 20, end: 0, hour: 23, want: true},
+		{name: "结束于 0 点的 0 点", start: 20, end: 0, hour: 0, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := serviceWithConfig(Config{QuietHoursStart: tt.start, QuietHoursEnd: tt.end})
+			now := time.Date(2024, 3, 1, tt.hour, 30, 0, 0, time.UTC)
+			if got := s.inQuietHours(now); got != tt.want {
+				t.Errorf("inQuietHours(%02d:30) = %v, 期望 %v", tt.hour, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "缺失", value: "", want: 0},
+		{name: "秒数", value: "30", want: 30 * time.Second},
+		{name: "零秒", value: "0", want: 0},
+		{name: "负数", value: "-5", want: 0},
+		{name: "超过上限的秒数", value: "3600", want: maxRetryAfter},
+		{name: "HTTP 日期", value: "Fri, 01 Mar 2024 12:00:45 GMT", want: 45 * time.Second},
+		{name: "已经过去的 HTTP 日期", value: "Fri, 01 Mar 2024 11:59:00 GMT", want: 0},
+		{name: "超过上限的 HTTP 日期", value: "Fri, 01 Mar 2024 13:00:00 GMT", want: maxRetryAfter},
+		{name: "无效内容", value: "soon", want: 0},
+		{name: "带小数的秒数", value: "1.5", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, 期望 %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// 每日报告在配置的整点发送，同一小时内多次检查只发送一次，跨天、跨月后重新发送
+func TestShouldSendDailyReport(t *testing.T) {
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name       string
+		hours      []int
+		lastReport time.Time
+		now        time.Time
+		want       bool
+	}{
+		{name: "首次运行到达发送时间", hours: []int{0}, now: at(3, 1, 0, 5), want: true},
+		{name: "首次运行未到发送时间", hours: []int{0}, now: at(2, 29, 23, 59), want: false},
+		{name: "午夜前一分钟", hours: []int{0}, lastReport: at(2, 28, 0, 0), now: at(2, 29, 23, 59), want: false},
+		{name: "跨过午夜", hours: []int{0}, lastReport: at(2, 28, 0, 0), now: at(2, 29, 0, 0), want: true},
+		{name: "同一小时内已发送", hours: []int{0}, lastReport: at(3, 1, 0, 0), now: at(3, 1, 0, 5), want: false},
+		{name: "跨月的同一日号", hours: []int{0}, lastReport: at(2, 1, 0, 3), now: at(3, 1, 0, 5), want: true},
+		{name: "跨月的月末到月初", hours: []int{23}, lastReport: at(2, 29, 23, 0), now: at(3, 1, 23, 30), want: true},
+		{name: "同一天的另一个发送时间", hours: []int{0, 12}, lastReport: at(3, 1, 0, 0), now: at(3, 1, 12, 1), want: true},
+		{name: "不在发送时间", hours: []int{0, 12}, lastReport: at(3, 1, 0, 0), now: at(3, 1, 6, 0), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := serviceWithConfig(Config{DailyReportHours: tt.hours})
+			now := tt.now
+			s.Now = func() time.Time { return now }
+			if !tt.lastReport.IsZero() {
+				s.markReportSent(tt.lastReport)
+			}
+			if got := s.shouldSendDailyReport(); got != tt.want {
+				t.Errorf("shouldSendDailyReport() at %s = %v, 期望 %v", now.Format(time.RFC3339), got, tt.want)
+			}
+		})
+	}
+}
+
+// 按 00:05 之后每分钟检查一次模拟主循环，发送时间附近只发送一次报告
+func TestShouldSendDailyReportAcrossMidnight(t *testing.T) {
+	s := serviceWithConfig(Config{DailyReportHours: []int{0}})
+	now := time.Date(2024, 2, 29, 23, 50, 0, 0, time.UTC)
+	s.Now = func() time.Time { return now }
+	s.markReportSent(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+
+	var sent []time.Time
+	for end := time.Date(2024, 3, 1, 0, 5, 0, 0, time.UTC); !now.After(end); now = now.Add(time.Minute) {
+		if s.shouldSendDailyReport() {
+			sent = append(sent, now)
+			s.markReportSent(now)
+		}
+	}
+	if len(sent) != 1 || !sent[0].Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("23:50 到 00:05 之间发送报告的时间 = %v, 期望只在 00:00 发送一次", sent)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Maintenance 计划维护
+type Maintenance struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Status         string    `json:"status"`
+	Impact         string    `json:"impact"`
+	Shortlink      string    `json:"shortlink"`
+	ScheduledFor   time.Time `json:"scheduled_for"`
+	ScheduledUntil time.Time `json:"scheduled_until"`
+	Page           string    `json:"page,omitempty"` // 维护所属的状态页地址
+}
+
+// MaintenancesResponse 结构体用于解析 scheduled-maintenances/upcoming.json
+type MaintenancesResponse struct {
+	ScheduledMaintenances []Maintenance `json:"scheduled_maintenances"`
+}
+
+// 获取单个状态页即将进行的计划维护
+func (s *Service) fetchPageMaintenances(ctx context.Context, page string) ([]Maintenance, error) {
+	resp, err := s.getStatusPage(ctx, page, "/api/v2/scheduled-maintenances/upcoming.json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取计划维护失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := readLimited(resp.Body, s.config().MaxResponseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("读取计划维护失败: %v", err)
+	}
+
+	var response MaintenancesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析计划维护失败: %v", err)
+	}
+	for i := range response.ScheduledMaintenances {
+		response.ScheduledMaintenances[i].Page = page
+	}
+	return response.ScheduledMaintenances, nil
+}
+
+// 获取所有状态页即将进行的计划维护，单个状态页失败时只记录日志，按开始时间排序
+func (s *Service) fetchUpcomingMaintenances(ctx context.Context) []Maintenance {
+	var maintenances []Maintenance
+	for _, page := range s.config().StatusPages {
+		pageMaintenances, err := s.fetchPageMaintenances(ctx, page)
+		if err != nil {
+			logErrorf("状态页 %s: %v", page, err)
+			continue
+		}
+		maintenances = append(maintenances, pageMaintenances...)
+	}
+	sort.Slice(maintenances, func(i, j int) bool {
+		return maintenances[i].ScheduledFor.Before(maintenances[j].ScheduledFor)
+	})
+	return maintenances
+}
+
+// 生成每日报告中的计划维护部分
+func (s *Service) formatMaintenances(maintenances []Maintenance) string {
+	var section strings.Builder
+	section.WriteString("## 计划维护\n\n")
+	if len(maintenances) == 0 {
+		section.WriteString("近期没有计划维护。\n\n")
+		return section.String()
+	}
+	for _, maintenance := range maintenances {
+		section.WriteString(fmt.Sprintf("- %s（%s ~ %s，影响程度: %s）",
+			maintenance.Name,
+			s.formatTime(maintenance.ScheduledFor),
+			s.formatTime(maintenance.ScheduledUntil),
+			maintenance.Impact))
+		if maintenance.Shortlink != "" {
+			section.WriteString(fmt.Sprintf(" [详情](%s)", maintenance.Shortlink))
+		}
+		section.WriteString("\n")
+	}
+	section.WriteString("\n")
+	return section.String()
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 通知类型
+const (
+	notifyKindStartup       = "startup"
+	notifyKindChange        = "change"
+	notifyKindDailyReport   = "daily_report"
+	notifyKindHealth        = "health"
+	notifyKindComponent     = "component"
+	notifyKindLongIncident  = "long_incident"
+	notifyKindOverallStatus = "overall_status"
+	notifyKindCatchUp       = "catch_up"
+	notifyKindApproval      = "approval"
+	notifyKindUptime        = "uptime_sla"
+	notifyKindStatusBoard   = "status_board"
+)
+
+// 事件变化类型
+const (
+	changeTypeNew      = "new"
+	changeTypeUpdate   = "update"
+	changeTypeResolved = "resolved"
+	changeTypeReopened = "reopened"
+)
+
+// IncidentEvent 描述一次事件变化，供结构化通知渠道使用
+type IncidentEvent struct {
+	ChangeType string
+	Incident   Incident
+}
+
+// Notification 一条待发送的通知
+type Notification struct {
+	Kind    string
+	Title   string
+	Content string // 钉钉风格的 Markdown 正文
+	Events  []IncidentEvent
+	AtAll   bool // 是否需要提醒所有人（钉钉 isAtAll）
+
+	// 配置 DINGTALK_ROUTES 时变更通知按关键词路由拆分：Route 非空的通知只由钉钉发送到对应的机器人，
+	// SkipDingtalk 的通知包含全部变化，只发送给其他渠道
+	Route        string
+	SkipDingtalk bool
+}
+
+// Notifier 通知渠道接口
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// dingtalkNotifier 钉钉机器人通知渠道
+type dingtalkNotifier struct {
+	service *Service
+	rt      *runtimeConfig // 创建该渠道时的配置，发送期间不受重新加载影响
+
+	// 连续认证失败达到阈值后改用 fallback 发送，fallback 为 nil 时只记录错误
+	fallback     Notifier
+	mutex        sync.Mutex
+	authFailures int
+
+	queue *deliveryQueue // 发送失败的消息持久化队列，未配置 NOTIFY_QUEUE_FILE 时为 nil
+
+	sentKeys *sentKeyStore // 最近已发送变更通知的去重键，未配置 NOTIFY_DEDUP_FILE 时为 nil
+}
+
+func (d *dingtalkNotifier) Name() string {
+	return "dingtalk"
+}
+
+// 钉钉 Markdown 消息正文的最大字节数
+const dingtalkMaxMessageBytes = 20000
+
+// dingtalkTarget 一个钉钉机器人的发送目标
+type dingtalkTarget struct {
+	name   string
+	token  string
+	secret string
+}
+
+// 按名称查找钉钉机器人，用于重新投递队列中的消息；对应机器人已不再配置时使用默认机器人
+func (d *dingtalkNotifier) targetByName(name string) dingtalkTarget {
+	config := &d.rt.config
+	if target, ok := d.routeTarget(name); ok {
+		return target
+	}
+	switch {
+	case name == "critical" && config.DingtalkCriticalWebhook != "":
+		return dingtalkTarget{name: "critical", token: config.DingtalkCriticalWebhook, secret: config.DingtalkCriticalSecret}
+	case name == "info" && config.DingtalkInfoWebhook != "":
+		return dingtalkTarget{name: "info", token: config.DingtalkInfoWebhook, secret: config.DingtalkInfoSecret}
+	}
+	return dingtalkTarget{name: "default", token: config.DingtalkWebhookToken, secret: config.DingtalkSecret}
+}
+
+// 按名称查找关键词路由规则对应的钉钉机器人
+func (d *dingtalkNotifier) routeTarget(name string) (dingtalkTarget, bool) {
+	for _, route := range d.rt.config.DingtalkRoutes {
+		if route.name() == name {
+			return dingtalkTarget{name: name, token: route.Token, secret: route.Secret}, true
+		}
+	}
+	return dingtalkTarget{}, false
+}
+
+// 选择钉钉机器人：关键词路由通知使用路由规则的机器人；其余按事件的最高影响程度选择，
+// 包含 critical 事件时使用 critical 机器人，其余事件变化使用 info 机器人，
+// 未配置对应机器人或不是事件通知时使用默认机器人
+func (d *dingtalkNotifier) targetFor(n Notification) dingtalkTarget {
+	config := &d.rt.config
+	if target, ok := d.routeTarget(n.Route); ok {
+		return target
+	}
+	target := dingtalkTarget{name: "default", token: config.DingtalkWebhookToken, secret: config.DingtalkSecret}
+	if len(n.Events) == 0 {
+		return target
+	}
+
+	maxRank := 0
+	for _, event := range n.Events {
+		if rank := impactRank[event.Incident.Impact]; rank > maxRank {
+			maxRank = rank
+		}
+	}
+	if maxRank >= impactRank["critical"] {
+		if config.DingtalkCriticalWebhook != "" {
+			return dingtalkTarget{name: "critical", token: config.DingtalkCriticalWebhook, secret: config.DingtalkCriticalSecret}
+		}
+	} else if config.DingtalkInfoWebhook != "" {
+		return dingtalkTarget{name: "info", token: config.DingtalkInfoWebhook, secret: config.DingtalkInfoSecret}
+	}
+	return target
+}
+
+// 发送通知。配置了去重记录时，TTL 内已发送过的相同变更通知直接跳过，
+// 避免滚动部署期间新旧实例重叠或多实例误配置时重复告警
+func (d *dingtalkNotifier) Send(ctx context.Context, n Notification) error {
+	if d.sentKeys == nil || len(n.Events) == 0 {
+		return d.deliver(ctx, n)
+	}
+	key := notificationDedupKey(n.Route, n.Events)
+	if !d.sentKeys.Claim(key) {
+		logEvent("info", "dingtalk", logFields{"title": n.Title, "dedup_key": key},
+			"相同的变更通知在 %d 分钟内已发送过，跳过重复通知 - 标题: %s", d.rt.config.NotifyDedupTTLMinutes, n.Title)
+		return nil
+	}
+	if err := d.deliver(ctx, n); err != nil {
+		d.sentKeys.Release(key)
+		return err
+	}
+	return nil
+}
+
+func (d *dingtalkNotifier) deliver(ctx context.Context, n Notification) error {
+	pending, err := d.send(ctx, n)
+	failures := d.recordAuthResult(err)
+	if failures < d.rt.config.DingtalkAuthFailureThreshold {
+		d.enqueue(pending, err)
+		return err
+	}
+
+	// 凭证很可能已被轮换或失效，重试不会恢复，需要人工处理
+	msg := fmt.Sprintf("钉钉机器人连续 %d 次认证失败，凭证可能已失效，请检查 DINGTALK_WEBHOOK_TOKEN 和 DINGTALK_SECRET: %v", failures, err)
+	fmt.Fprintf(os.Stderr, "!!! %s\n", msg)
+	logEvent("error", "dingtalk", logFields{"auth_failures": failures, "error": err.Error()}, "%s", msg)
+	if d.fallback == nil {
+		d.enqueue(pending, err)
+		return err
+	}
+
+	logInfof("通过备用渠道 %s 发送通知 - 标题: %s", d.fallback.Name(), n.Title)
+	n.Content = fmt.Sprintf("> ⚠️ 钉钉机器人连续 %d 次认证失败，本通知改由备用渠道发送，请检查钉钉机器人配置\n\n", failures) + n.Content
+	if fallbackErr := d.fallback.Send(ctx, n); fallbackErr != nil {
+		d.enqueue(pending, err)
+		return fmt.Errorf("%v；备用渠道 %s 也发送失败: %v", err, d.fallback.Name(), fallbackErr)
+	}
+	return nil
+}
+
+// 按顺序发送拆分后的消息，失败时返回失败的部分及其后尚未发送的部分
+func (d *dingtalkNotifier) send(ctx context.Context, n Notification) ([]queuedMessage, error) {
+	target := d.targetFor(n)
+	parts := splitMessage(n.Content, dingtalkMaxMessageBytes)
+	for i, part := range parts {
+		title := partTitle(n.Title, i, len(parts))
+		if err := d.service.sendDingtalkNotification(ctx, d.rt, target, title, part, n.AtAll); err != nil {
+			now := d.service.Now()
+			pending := []queuedMessage{{Target: target.name, Title: title, Content: part, AtAll: n.AtAll, EnqueuedAt: now}}
+			for j := i + 1; j < len(parts); j++ {
+				pending = append(pending, queuedMessage{Target: target.name, Title: partTitle(n.Title, j, len(parts)),
+					Content: parts[j], AtAll: n.AtAll, EnqueuedAt: now})
+			}
+			return pending, err
+		}
+	}
+	return nil, nil
+}
+
+// 启用投递队列时保存未送达的消息，等待下一轮检查时重试
+func (d *dingtalkNotifier) enqueue(pending []queuedMessage, err error) {
+	if d.queue == nil || len(pending) == 0 {
+		return
+	}
+	for i := range pending {
+		pending[i].Attempts = 1
+		pending[i].LastError = err.Error()
+	}
+	d.queue.add(pending)
+}
+
+// 记录发送结果并返回当前连续认证失败次数：认证类错误累加，成功时清零，
+// 限流等其他错误不影响计数
+func (d *dingtalkNotifier) recordAuthResult(err error) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var dtErr *dingtalkError
+	switch {
+	case err == nil:
+		if d.authFailures > 0 {
+			logInfof("钉钉机器人认证已恢复")
+		}
+		d.authFailures = 0
+	case errors.As(err, &dtErr) && dtErr.authFailure():
+		d.authFailures++
+	default:
+		return 0
+	}
+	return d.authFailures
+}
+
+// 将超过 limit 字节的消息拆分为多条。优先在 Markdown 标题处拆分，
+// 单个段落仍然过长时按行拆分，单行过长时按字符截断，不会拆开多字节字符
+func splitMessage(content string, limit int) []string {
+	if len(content) <= limit {
+		return []string{content}
+	}
+
+	// 按标题行把正文切分为段落
+	var blocks []string
+	var block strings.Builder
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if strings.HasPrefix(line, "#") && block.Len() > 0 {
+			blocks = append(blocks, block.String())
+			block.Reset()
+		}
+		block.WriteString(line)
+	}
+	if block.Len() > 0 {
+		blocks = append(blocks, block.String())
+	}
+
+	var parts []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+	add := func(piece string) {
+		if current.Len()+len(piece) > limit {
+			flush()
+		}
+		current.WriteString(piece)
+	}
+	for _, block := range blocks {
+		if len(block) <= limit {
+			add(block)
+			continue
+		}
+		for _, line := range strings.SplitAfter(block, "\n") {
+			for len(line) > limit {
+				cut := limit
+				for cut > 0 && !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+				flush()
+				parts = append(parts, line[:cut])
+				line = line[cut:]
+			}
+			add(line)
+		}
+	}
+	flush()
+	return parts
+}
+
+// 拆分后的消息标题加上序号
+func partTitle(title string, index, total int) string {
+	if total <= 1 {
+		return title
+	}
+	return fmt.Sprintf("%s (%d/%d)", title, index+1, total)
+}
+
+// 按 rt 中的配置创建通知渠道，钉钉限流器和熔断器写入 rt
+func buildNotifiers(s *Service, rt *runtimeConfig) ([]Notifier, error) {
+	if rt.config.ObserveOnly {
+		logInfof("OBSERVE_ONLY 已启用，只采集数据，不初始化任何通知渠道")
+		return nil, nil
+	}
+	var notifiers []Notifier
+	for _, name := range rt.config.Notifiers {
+		notifier, err := newNotifier(s, rt, name)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	return notifiers, nil
+}
+
+// 按名称创建单个通知渠道。投递队列和去重记录对应磁盘文件，只在首次创建时打开，重新加载配置时复用
+func newNotifier(s *Service, rt *runtimeConfig, name string) (Notifier, error) {
+	config := &rt.config
+	switch name {
+	case "dingtalk":
+		rt.dingtalkLimiter = newRateLimiter(config.DingtalkRateLimit)
+		if config.CBFailureThreshold > 0 {
+			rt.dingtalkBreaker = newCircuitBreaker(config.CBFailureThreshold, time.Duration(config.CBCooldownSeconds)*time.Second)
+		}
+		dingtalk := &dingtalkNotifier{service: s, rt: rt}
+		if config.NotifyQueueFile != "" {
+			if s.notifyQueue == nil {
+				queue, err := openDeliveryQueue(config.NotifyQueueFile, time.Duration(config.NotifyQueueMaxAgeHours)*time.Hour)
+				if err != nil {
+					return nil, err
+				}
+				s.notifyQueue = queue
+			}
+			dingtalk.queue = s.notifyQueue
+		}
+		if config.NotifyDedupFile != "" {
+			if s.sentKeys == nil {
+				s.sentKeys = newSentKeyStore(config.NotifyDedupFile, time.Duration(config.NotifyDedupTTLMinutes)*time.Minute, s.Now)
+			}
+			dingtalk.sentKeys = s.sentKeys
+		}
+		if config.DingtalkFallbackNotifier != "" {
+			fallback, err := newNotifier(s, rt, config.DingtalkFallbackNotifier)
+			if err != nil {
+				return nil, err
+			}
+			dingtalk.fallback = fallback
+		}
+		return dingtalk, nil
+	case "webhook":
+		webhook := newWebhookNotifier(config.WebhookURL, config.WebhookToken, config.WebhookSigningSecret, config.MaxResponseBytes, s.Now)
+		if config.ThreadUpdates {
+			webhook.threads = s.threads
+		}
+		return webhook, nil
+	case "feishu":
+		return newFeishuNotifier(config.FeishuWebhook, config.FeishuSecret, config.MaxResponseBytes, s.Now), nil
+	case "wechat_work":
+		return newWechatWorkNotifier(config.WechatWorkWebhookKey, config.MaxResponseBytes), nil
+	case "ntfy":
+		return newNtfyNotifier(config.NtfyURL, config.NtfyToken, config.MaxResponseBytes), nil
+	default:
+		return nil, fmt.Errorf("未知的通知渠道: %s", name)
+	}
+}
+
+// 将通知提交到发送队列，按提交顺序发送到所有已注册的渠道，任一渠道失败都会返回错误
+func (s *Service) notify(ctx context.Context, n Notification) error {
+	return s.dispatch(ctx, func() error {
+		return s.deliver(ctx, n)
+	})
+}
+
+// 将通知依次发送到各渠道，只在发送 goroutine 中调用。整条通知使用同一份配置和渠道列表，
+// 发送期间重新加载配置不影响本次发送
+func (s *Service) deliver(ctx context.Context, n Notification) error {
+	rt := s.snapshot()
+	if rt.config.ObserveOnly {
+		logEvent("debug", "notify", logFields{"kind": n.Kind, "observe_only": true}, "OBSERVE_ONLY 模式，不发送通知 - 标题: %s", n.Title)
+		return nil
+	}
+	var failed []string
+	for _, notifier := range rt.notifiers {
+		_, isDingtalk := notifier.(*dingtalkNotifier)
+		if (n.Route != "" && !isDingtalk) || (n.SkipDingtalk && isDingtalk) {
+			continue
+		}
+		if err := notifier.Send(ctx, n); err != nil {
+			logEvent("error", "notify", logFields{"notifier": notifier.Name(), "kind": n.Kind, "error": err.Error()},
+				"通过 %s 发送通知失败: %v", notifier.Name(), err)
+			failed = append(failed, notifier.Name())
+			continue
+		}
+		logEvent("info", "notify", logFields{"notifier": notifier.Name(), "kind": n.Kind},
+			"通过 %s 发送通知成功", notifier.Name())
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("以下通知渠道发送失败: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
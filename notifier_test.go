@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// blockingNotifier 在 release 关闭之前阻塞发送，用于模拟缓慢的通知渠道
+type blockingNotifier struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newBlockingNotifier() *blockingNotifier {
+	return &blockingNotifier{entered: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (b *blockingNotifier) Name() string {
+	return "blocking"
+}
+
+func (b *blockingNotifier) Send(ctx context.Context, n Notification) error {
+	b.entered <- struct{}{}
+	<-b.release
+	return nil
+}
+
+// 发送通知期间不持有锁：通知卡住时仍能读写事件缓存，需要配合 go test -race 运行
+func TestCheckForChangesSendsOutsideLock(t *testing.T) {
+	notifier := newBlockingNotifier()
+	s := newTestService(t, "")
+	setTestNotifiers(s, notifier)
+	incident := testIncident("inc1", "investigating", "minor", time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		s.checkForChanges(context.Background(), []Incident{incident})
+		close(done)
+	}()
+	select {
+	case <-notifier.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("启动通知未开始发送")
+	}
+
+	cached := make(chan int, 1)
+	go func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		cached <- len(s.lastIncidents)
+	}()
+	select {
+	case n := <-cached:
+		if n != 1 {
+			t.Errorf("缓存的事件数量 = %d, 期望 1", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("发送通知期间获取锁被阻塞，锁没有在发送前释放")
+	}
+	close(notifier.release)
+	<-done
+}
+
+func TestSplitMessage(t *testing.T) {
+	section := func(title string, lines int) string {
+		var b strings.Builder
+		b.WriteString("## " + title + "\n\n")
+		for i := 0; i < lines; i++ {
+			b.WriteString(fmt.Sprintf("- 状态: 正在调查 第 %d 行，影响部分地区的访问\n", i))
+		}
+		return b.String()
+	}
+	longLine := strings.Repeat("中文内容", 1500) + "\n"
+	tests := []struct {
+		name      string
+		content   string
+		limit     int
+		wantParts int // 0 表示不检查段数
+	}{
+		{name: "未超过限制", content: section("事件", 3), limit: dingtalkMaxMessageBytes, wantParts: 1},
+		{name: "恰好等于限制", content: strings.Repeat("中", dingtalkMaxMessageBytes/3) + "ab", limit: dingtalkMaxMessageBytes, wantParts: 1},
+		{name: "超过限制一个字节", content: strings.Repeat("a", dingtalkMaxMessageBytes+1), limit: dingtalkMaxMessageBytes, wantParts: 2},
+		{name: "钉钉按标题拆分", content: section("事件一", 150) + section("事件二", 150) + section("事件三", 150), limit: dingtalkMaxMessageBytes},
+		{name: "企业微信按行拆分", content: section("事件", 120), limit: wechatWorkMaxMessageBytes},
+		{name: "单行超过企业微信限制", content: "## 事件\n" + longLine, limit: wechatWorkMaxMessageBytes},
+		{name: "单行超过钉钉限制", content: strings.Repeat(longLine[:len(longLine)-1], 2), limit: dingtalkMaxMessageBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := splitMessage(tt.content, tt.limit)
+			if tt.wantParts > 0 && len(parts) != tt.wantParts {
+				t.Errorf("拆分为 %d 段, 期望 %d 段", len(parts), tt.wantParts)
+			}
+			if len(tt.content) > tt.limit && len(parts) < 2 {
+				t.Errorf("超过 %d 字节的消息没有被拆分", tt.limit)
+			}
+			for i, part := range parts {
+				if len(part) > tt.limit {
+					t.Errorf("第 %d 段 %d 字节, 超过限制 %d", i+1, len(part), tt.limit)
+				}
+				if !utf8.ValidString(part) {
+					t.Errorf("第 %d 段拆开了多字节字符", i+1)
+				}
+				if part == "" {
+					t.Errorf("第 %d 段为空", i+1)
+				}
+			}
+			if joined := strings.Join(parts, ""); joined != tt.content {
+				t.Error("拆分后的内容拼接起来与原文不一致")
+			}
+		})
+	}
+}
+
+func TestPartTitle(t *testing.T) {
+	tests := []struct {
+		index, total int
+		want         string
+	}{
+		{index: 0, total: 1, want: "Cloudflare 状态更新"},
+		{index: 0, total: 3, want: "Cloudflare 状态更新 (1/3)"},
+		{index: 2, total: 3, want: "Cloudflare 状态更新 (3/3)"},
+	}
+	for _, tt := range tests {
+		if got := partTitle("Cloudflare 状态更新", tt.index, tt.total); got != tt.want {
+			t.Errorf("partTitle(%d, %d) = %q, 期望 %q", tt.index, tt.total, got, tt.want)
+		}
+	}
+}
+
+// 自定义的标题和页脚模板在创建 runtimeConfig 时解析，生成每条通知时直接执行
+func TestCustomTitleAndFooter(t *testing.T) {
+	s := newTestService(t, "NEW_TITLE_TEMPLATE={{.Emoji}} {{.Count}} 个新事件\n"+
+		"UPDATE_TITLE_TEMPLATE=事件更新{{if .Name}}: {{.Name}}{{end}}\n"+
+		`NOTIFICATION_FOOTER=更多信息: {{.StatusPageURL}}\n共 {{len .StatusPageURLs}} 个状态页`+"\n")
+	newChange := incidentChange{Event: IncidentEvent{ChangeType: changeTypeNew, Incident: testIncident("inc1", "investigating", "major", time.Hour)}}
+	update := incidentChange{Event: IncidentEvent{ChangeType: changeTypeUpdate, Incident: testIncident("inc2", "identified", "minor", time.Hour)}}
+
+	if got, want := s.changeTitle([]incidentChange{newChange}, false), impactEmojis["major"]+" 1 个新事件"; got != want {
+		t.Errorf("新事件标题 = %q, 期望 %q", got, want)
+	}
+	if got, want := s.changeTitle([]incidentChange{update}, true), "事件更新: Incident inc2"; got != want {
+		t.Errorf("更新标题 = %q, 期望 %q", got, want)
+	}
+	if got, want := s.notificationFooter([]string{"https://a.example.com", "https://b.example.com"}), "更多信息: https://a.example.com\n共 2 个状态页"; got != want {
+		t.Errorf("页脚 = %q, 期望 %q", got, want)
+	}
+}
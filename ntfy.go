@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ntfy 消息正文的最大字节数，超过时服务端会把消息转为附件
+const ntfyMaxMessageBytes = 4096
+
+// 影响程度对应的 ntfy 优先级
+var ntfyPriorities = map[string]string{
+	"critical": "urgent",
+	"major":    "high",
+	"minor":    "default",
+	"none":     "low",
+}
+
+// 影响程度对应的 ntfy 标签，ntfy 客户端会把这些标签显示为 emoji
+var ntfyImpactTags = map[string]string{
+	"critical": "rotating_light",
+	"major":    "warning",
+	"minor":    "large_orange_diamond",
+	"none":     "information_source",
+}
+
+// ntfyNotifier 推送到 ntfy 主题，正文为纯文本
+type ntfyNotifier struct {
+	url              string // 主题地址，如 https://ntfy.sh/cf-status
+	token            string
+	maxResponseBytes int64
+}
+
+func newNtfyNotifier(url, token string, maxResponseBytes int64) *ntfyNotifier {
+	return &ntfyNotifier{url: url, token: token, maxResponseBytes: maxResponseBytes}
+}
+
+func (t *ntfyNotifier) Name() string {
+	return "ntfy"
+}
+
+func (t *ntfyNotifier) Send(ctx context.Context, n Notification) error {
+	priority, tags := ntfyPriorityAndTags(n)
+	parts := splitMessage(toNtfyPlainText(n.Content), ntfyMaxMessageBytes)
+	for i, part := range parts {
+		title := partTitle(n.Title, i, len(parts))
+		logDebugf("准备发送 ntfy 通知 - 标题: %s", title)
+		if err := t.post(ctx, title, part, priority, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 根据通知中影响程度最高的事件选择优先级和标签；没有事件的通知（启动通知、每日报告等）使用默认优先级
+func ntfyPriorityAndTags(n Notification) (string, string) {
+	if len(n.Events) == 0 {
+		return "default", "cloud"
+	}
+	highest := n.Events[0].Incident.Impact
+	allResolved := true
+	for _, event := range n.Events {
+		if impactRank[event.Incident.Impact] > impactRank[highest] {
+			highest = event.Incident.Impact
+		}
+		allResolved = allResolved && event.Incident.isResolved()
+	}
+
+	priority, ok := ntfyPriorities[highest]
+	if !ok {
+		priority = "default"
+	}
+	tags := []string{"cloud"}
+	if allResolved {
+		tags = append(tags, "white_check_mark")
+	} else if tag, ok := ntfyImpactTags[highest]; ok {
+		tags = append(tags, tag)
+	}
+	if n.AtAll && priority != "urgent" {
+		priority = "high"
+	}
+	return priority, strings.Join(tags, ",")
+}
+
+func (t *ntfyNotifier) post(ctx context.Context, title, body, priority, tags string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建 ntfy 请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	// 标题包含中文，按 RFC 2047 编码后放入请求头
+	req.Header.Set("X-Title", mime.BEncoding.Encode("utf-8", title))
+	req.Header.Set("X-Priority", priority)
+	req.Header.Set("X-Tags", tags)
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送 ntfy 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := readLimited(resp.Body, t.maxResponseBytes)
+	logDebugf("ntfy 响应: HTTP状态码=%d, 响应内容=%s", resp.StatusCode, string(respBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("ntfy 返回异常状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+var (
+	markdownLinkPattern = regexp.MustCompile(`\[([^\]]*)\]\(([^)]*)\)`)
+	markdownBoldPattern = regexp.MustCompile(`\*\*([^*]*)\*\*`)
+	blankLinesPattern   = regexp.MustCompile(`\n{3,}`)
+)
+
+// 将钉钉风格的 Markdown 降级为纯文本：去掉标题符号、加粗、引用符号、字体颜色标签和分隔线，
+// 链接改写为 "文字 (地址)"，并合并多余的空行
+func toNtfyPlainText(content string) string {
+	content = fontColorPattern.ReplaceAllString(content, "")
+	content = strings.ReplaceAll(content, "</font>", "")
+	content = markdownLinkPattern.ReplaceAllString(content, "$1 ($2)")
+	content = markdownBoldPattern.ReplaceAllString(content, "$1")
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, "#")
+		if trimmed != line && strings.HasPrefix(trimmed, " ") {
+			line = strings.TrimSpace(trimmed)
+		}
+		if strings.HasPrefix(line, "> ") {
+			line = strings.TrimPrefix(line, "> ")
+		}
+		if strings.TrimSpace(line) == "---" {
+			line = ""
+		}
+		// 续行转义的标题和引用符号在纯文本中不再需要
+		if strings.HasPrefix(strings.TrimSpace(line), "\\#") || strings.HasPrefix(strings.TrimSpace(line), "\\>") {
+			line = strings.Replace(line, "\\", "", 1)
+		}
+		lines[i] = line
+	}
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")) + "\n"
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Status 状态页整体状态
+type Status struct {
+	Indicator   string `json:"indicator"` // none、minor、major、critical
+	Description string `json:"description"`
+}
+
+// StatusResponse 结构体用于解析 status.json
+type StatusResponse struct {
+	Status Status `json:"status"`
+}
+
+// 整体状态指示的中文描述
+var overallIndicatorNames = map[string]string{
+	"none":        "正常",
+	"minor":       "轻微影响",
+	"major":       "部分中断",
+	"critical":    "严重中断",
+	"maintenance": "维护中",
+}
+
+func overallIndicatorName(indicator string) string {
+	if name, ok := overallIndicatorNames[indicator]; ok {
+		return name
+	}
+	return indicator
+}
+
+// 获取单个状态页的整体状态
+func (s *Service) fetchPageStatus(ctx context.Context, page string) (Status, error) {
+	resp, err := s.getStatusPage(ctx, page, "/api/v2/status.json", nil)
+	if err != nil {
+		return Status{}, fmt.Errorf("获取整体状态失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := readLimited(resp.Body, s.config().MaxResponseBytes)
+	if err != nil {
+		return Status{}, fmt.Errorf("读取整体状态失败: %v", err)
+	}
+
+	var response StatusResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return Status{}, fmt.Errorf("解析整体状态失败: %v", err)
+	}
+	if response.Status.Indicator == "" {
+		return Status{}, fmt.Errorf("整体状态缺少 indicator 字段")
+	}
+	return response.Status, nil
+}
+
+// 检查各状态页整体状态指示的变化并发送通知，首次获取时只记录不通知
+func (s *Service) checkOverallStatus(ctx context.Context) {
+	var changes []string
+	for _, page := range s.config().StatusPages {
+		status, err := s.fetchPageStatus(ctx, page)
+		if err != nil {
+			logErrorf("状态页 %s: %v", page, err)
+			continue
+		}
+
+		s.mutex.Lock()
+		if s.overallStatus == nil {
+			s.overallStatus = make(map[string]Status)
+		}
+		old, exists := s.overallStatus[page]
+		s.overallStatus[page] = status
+		s.mutex.Unlock()
+
+		if !exists || old.Indicator == status.Indicator {
+			continue
+		}
+		logInfof("整体状态变化 - 状态页: %s, %s -> %s", page, old.Indicator, status.Indicator)
+		line := fmt.Sprintf("- 整体状态: %s → %s", overallIndicatorName(old.Indicator), overallIndicatorName(status.Indicator))
+		if status.Description != "" {
+			line += fmt.Sprintf("（%s）", status.Description)
+		}
+		if len(s.config().StatusPages) > 1 {
+			line += fmt.Sprintf(" - %s", s.pageName(page))
+		}
+		changes = append(changes, line+"\n")
+	}
+
+	if len(changes) == 0 {
+		return
+	}
+
+	s.mutex.RLock()
+	header := notificationHeader(s.statusVersion, s.formatTime(s.Now()))
+	s.mutex.RUnlock()
+	content := "# Cloudflare 整体状态变化\n\n" + header +
+		strings.Join(changes, "") + "\n---\n" +
+		s.notificationFooter(nil)
+	if err := s.notify(ctx, Notification{
+		Kind:    notifyKindOverallStatus,
+		Title:   "Cloudflare 整体状态变化",
+		Content: content,
+	}); err != nil {
+		logErrorf("发送整体状态通知失败: %v", err)
+	}
+}
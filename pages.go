@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// 将用户填写的状态页地址规范化为站点根地址，各 API 地址都在其后拼接 /api/v2/...。
+// 支持直接粘贴 API 地址（如 https://www.cloudflarestatus.com/api/v2/incidents.json）、
+// 历史页或事件页地址，去掉查询参数、片段和末尾的斜杠；无法解析时只去掉末尾斜杠原样返回
+func normalizeStatusPageURL(value string) string {
+	value = strings.TrimSpace(value)
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return strings.TrimRight(value, "/")
+	}
+	path := u.Path
+	for _, marker := range []string{"/api/v2", "/incidents/", "/history"} {
+		if i := strings.Index(path, marker); i >= 0 {
+			path = path[:i]
+		}
+	}
+	u.Path = strings.TrimRight(path, "/")
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// 解析 STATUS_PAGES 中的一项，支持 "显示名称=地址" 格式。等号前的部分不含 "/" 和 ":" 时才视为名称，
+// 避免把地址中的等号误判为分隔符
+func parseStatusPageEntry(entry string) (name, page string) {
+	entry = strings.TrimSpace(entry)
+	if i := strings.Index(entry, "="); i > 0 && !strings.ContainsAny(entry[:i], "/:") {
+		name, entry = strings.TrimSpace(entry[:i]), entry[i+1:]
+	}
+	return name, normalizeStatusPageURL(entry)
+}
+
+// 从状态页地址推导显示名称：去掉 www. 和 status. 前缀，取主域名的第一段并去掉 status 后缀，
+// 如 www.cloudflarestatus.com -> Cloudflare、status.openai.com -> Openai；无法推导时返回主机名
+func derivePageName(page string) string {
+	u, err := url.Parse(page)
+	if err != nil || u.Hostname() == "" {
+		return page
+	}
+	host := u.Hostname()
+	label := strings.TrimPrefix(strings.TrimPrefix(host, "www."), "status.")
+	if i := strings.Index(label, "."); i > 0 {
+		label = label[:i]
+	}
+	label = strings.TrimSuffix(label, "status")
+	label = strings.TrimRight(label, "-_")
+	if label == "" || net.ParseIP(host) != nil {
+		return host
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// 状态页的显示名称，STATUS_PAGES 中配置了名称时使用配置的名称，否则从主机名推导
+func (s *Service) pageName(page string) string {
+	if page == "" {
+		page = s.config().StatusPageURL
+	}
+	if name, ok := s.config().PageNames[page]; ok {
+		return name
+	}
+	return derivePageName(page)
+}
+
+// 监控多个状态页时事件标题前的状态页名称，如 "【Cloudflare】"；只监控一个状态页时为空
+func (s *Service) pagePrefix(incident Incident) string {
+	if len(s.config().StatusPages) <= 1 {
+		return ""
+	}
+	return "【" + s.pageName(incident.Page) + "】"
+}
+
+// 启动时请求各状态页的 /api/v2/status.json，确认其为 Statuspage 兼容的状态页，失败时只记录警告
+func (s *Service) probeStatusPages(ctx context.Context) {
+	for _, page := range s.config().StatusPages {
+		status, err := s.fetchPageStatus(ctx, page)
+		if err != nil {
+			logWarnf("状态页 %s 探测失败，可能不是 Statuspage 兼容的状态页或地址有误（应为站点根地址，如 https://www.cloudflarestatus.com）: %v", page, err)
+			continue
+		}
+		logDebugf("状态页 %s 探测成功，当前整体状态: %s", page, overallIndicatorName(status.Indicator))
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// queuedMessage 发送失败、等待重新投递的钉钉消息
+type queuedMessage struct {
+	Target     string    `json:"target"` // 钉钉机器人名称: default、critical 或 info
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	AtAll      bool      `json:"at_all,omitempty"`
+	Attempts   int       `json:"attempts"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// deliveryQueue 持久化到磁盘的投递队列，进程重启后仍会继续重试
+type deliveryQueue struct {
+	path   string
+	maxAge time.Duration
+	mutex  sync.Mutex
+	items  []queuedMessage
+}
+
+// 打开投递队列并加载已有内容，文件不存在时视为空队列
+func openDeliveryQueue(path string, maxAge time.Duration) (*deliveryQueue, error) {
+	q := &deliveryQueue{path: path, maxAge: maxAge}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取投递队列失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &q.items); err != nil {
+		return nil, fmt.Errorf("解析投递队列失败: %v", err)
+	}
+	if len(q.items) > 0 {
+		logInfof("已从 %s 加载 %d 条待投递的通知", path, len(q.items))
+	}
+	return q, nil
+}
+
+// 写入队列文件，调用方需持有锁
+func (q *deliveryQueue) save() {
+	data, err := json.MarshalIndent(q.items, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(q.path, data, 0600)
+	}
+	if err != nil {
+		logErrorf("保存投递队列失败: %v", err)
+	}
+}
+
+// 将发送失败的消息加入队列
+func (q *deliveryQueue) add(messages []queuedMessage) {
+	if len(messages) == 0 {
+		return
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.items = append(q.items, messages...)
+	q.save()
+	logInfof("%d 条通知已加入投递队列，当前队列长度: %d", len(messages), len(q.items))
+}
+
+// 按入队顺序重新投递队列中的消息，超过最大保留时间的消息被丢弃，
+// 投递失败的消息保留到下一轮
+func (q *deliveryQueue) drain(ctx context.Context, deliver func(queuedMessage) error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.items) == 0 {
+		return
+	}
+
+	logInfof("开始重新投递队列中的 %d 条通知", len(q.items))
+	now := time.Now()
+	remaining := q.items[:0]
+	for _, msg := range q.items {
+		if now.Sub(msg.EnqueuedAt) > q.maxAge {
+			logEvent("error", "queue", logFields{"title": msg.Title, "attempts": msg.Attempts},
+				"通知超过最大保留时间 %v 仍未投递成功，已丢弃 - 标题: %s", q.maxAge, msg.Title)
+			continue
+		}
+		if ctx.Err() != nil {
+			remaining = append(remaining, msg)
+			continue
+		}
+		msg.Attempts++
+		if err := deliver(msg); err != nil {
+			msg.LastError = err.Error()
+			remaining = append(remaining, msg)
+			continue
+		}
+		logEvent("info", "queue", logFields{"title": msg.Title, "attempts": msg.Attempts},
+			"投递回执: 通知已送达 - 标题: %s, 入队时间: %s, 共尝试 %d 次",
+			msg.Title, msg.EnqueuedAt.Format("2006-01-02 15:04:05"), msg.Attempts)
+	}
+	q.items = remaining
+	q.save()
+}
+
+// 重新投递各通知渠道队列中的消息，每轮检查开始时调用。重投同样通过发送队列进行
+func (s *Service) retryQueuedNotifications(ctx context.Context) {
+	for _, notifier := range s.snapshot().notifiers {
+		if dingtalk, ok := notifier.(*dingtalkNotifier); ok && dingtalk.queue != nil {
+			dingtalk.queue.drain(ctx, func(msg queuedMessage) error {
+				return s.dispatch(ctx, func() error {
+					return s.sendDingtalkNotification(ctx, dingtalk.rt, dingtalk.targetByName(msg.Target), msg.Title, msg.Content, msg.AtAll)
+				})
+			})
+		}
+	}
+}
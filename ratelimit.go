@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter 令牌桶限流器
+type rateLimiter struct {
+	mutex    sync.Mutex
+	tokens   float64
+	capacity float64
+	interval time.Duration // 产生一个令牌所需的时间
+	last     time.Time
+}
+
+// 创建每分钟最多允许 perMinute 次的限流器
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		tokens:   float64(perMinute),
+		capacity: float64(perMinute),
+		interval: time.Minute / time.Duration(perMinute),
+		last:     time.Now(),
+	}
+}
+
+// 获取一个令牌，令牌不足时阻塞等待，返回实际等待的时间。
+// 等待期间 ctx 被取消时归还预先扣减的令牌并返回 ctx 的错误
+func (r *rateLimiter) Wait(ctx context.Context) (time.Duration, error) {
+	r.mutex.Lock()
+	now := time.Now()
+	r.tokens += float64(now.Sub(r.last)) / float64(r.interval)
+	if r.tokens > r.capacity {
+		r.tokens = r.capacity
+	}
+	r.last = now
+
+	// 预先扣减令牌，使并发的调用方按顺序排队
+	r.tokens--
+	var delay time.Duration
+	if r.tokens < 0 {
+		delay = time.Duration(-r.tokens * float64(r.interval))
+	}
+	r.mutex.Unlock()
+
+	if delay <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		r.mutex.Lock()
+		r.tokens++
+		r.mutex.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+// 在 d 时间内暂停发放令牌，用于配合服务端的 Retry-After。暂停结束时恰好有一个令牌可用，
+// 等待 Retry-After 的调用方可以立即重试，其他调用方继续按速率排队
+func (r *rateLimiter) Pause(d time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if until := time.Now().Add(d); until.After(r.last) {
+		r.tokens = 1
+		r.last = until
+	}
+}
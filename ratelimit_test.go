@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 令牌不足时等待可以被 ctx 取消，取消后归还预先扣减的令牌，不影响后续调用方排队
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	limiter := newRateLimiter(1)
+	if delay, err := limiter.Wait(context.Background()); err != nil || delay != 0 {
+		t.Fatalf("首个令牌 Wait() = %v, %v, 期望立即返回", delay, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := limiter.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() 错误 = %v, 期望 %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("取消后 Wait() 仍等待了 %v", elapsed)
+	}
+
+	limiter.mutex.Lock()
+	tokens := limiter.tokens
+	limiter.mutex.Unlock()
+	if tokens < -0.5 {
+		t.Errorf("取消后剩余令牌 = %.2f, 期望已归还预先扣减的令牌", tokens)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"text/template"
+	"time"
+)
+
+// 重新加载配置时保持不变的配置项：它们对应的资源只在启动时打开，修改后需要重启才能生效
+var restartOnlyKeys = []struct {
+	key   string
+	value func(Config) string
+}{
+	{"STATE_FILE", func(c Config) string { return c.StateFile }},
+	{"DB_PATH", func(c Config) string { return c.DBPath }},
+	{"HEALTH_LISTEN_ADDR", func(c Config) string { return c.HealthListenAddr }},
+	{"LOG_FILE", func(c Config) string { return c.LogFile }},
+	{"LOG_FORMAT", func(c Config) string { return c.LogFormat }},
+	{"LOG_MAX_SIZE_MB", func(c Config) string { return fmt.Sprint(c.LogMaxSizeMB) }},
+	{"NOTIFY_RATE_LIMIT_PER_MINUTE", func(c Config) string { return fmt.Sprint(c.NotifyRateLimit) }},
+	{"NOTIFY_QUEUE_FILE", func(c Config) string { return c.NotifyQueueFile }},
+	{"NOTIFY_QUEUE_MAX_AGE_HOURS", func(c Config) string { return fmt.Sprint(c.NotifyQueueMaxAgeHours) }},
+	{"NOTIFY_DEDUP_FILE", func(c Config) string { return c.NotifyDedupFile }},
+	{"NOTIFY_DEDUP_TTL_MINUTES", func(c Config) string { return fmt.Sprint(c.NotifyDedupTTLMinutes) }},
+}
+
+// runtimeConfig 运行期间生效的配置，以及据此创建的通知渠道、通知模板和钉钉限流器、熔断器。
+// 发布到 Service.current 后不再修改，重新加载时创建新的 runtimeConfig 整体替换，
+// 发送 goroutine、HTTP 接口和主循环读取时无需加锁，正在进行的发送继续使用读取时的那一份
+type runtimeConfig struct {
+	config          Config
+	notifiers       []Notifier
+	templates       *template.Template // 用户自定义通知模板，未配置时为 nil
+	dingtalkLimiter *rateLimiter       // 钉钉发送限流器
+	dingtalkBreaker *circuitBreaker    // 钉钉发送熔断器，CB_FAILURE_THRESHOLD 为 0 时为 nil
+
+	// 预先解析的 NOTIFICATION_FOOTER、NEW_TITLE_TEMPLATE 和 UPDATE_TITLE_TEMPLATE，每条通知直接执行
+	footerTemplate      *template.Template
+	newTitleTemplate    *template.Template
+	updateTitleTemplate *template.Template
+}
+
+// 按配置创建 runtimeConfig 并解析页脚和标题模板，通知渠道等由调用方填充
+func newRuntimeConfig(config Config) (*runtimeConfig, error) {
+	rt := &runtimeConfig{config: config}
+	var err error
+	if rt.footerTemplate, err = parseFooterTemplate(config.NotificationFooter); err != nil {
+		return nil, fmt.Errorf("NOTIFICATION_FOOTER 无效: %v", err)
+	}
+	if rt.newTitleTemplate, err = parseTitleTemplate("NEW_TITLE_TEMPLATE", config.NewTitleTemplate); err != nil {
+		return nil, fmt.Errorf("NEW_TITLE_TEMPLATE 无效: %v", err)
+	}
+	if rt.updateTitleTemplate, err = parseTitleTemplate("UPDATE_TITLE_TEMPLATE", config.UpdateTitleTemplate); err != nil {
+		return nil, fmt.Errorf("UPDATE_TITLE_TEMPLATE 无效: %v", err)
+	}
+	return rt, nil
+}
+
+// 当前生效的配置和通知渠道。需要多个字段保持一致时只调用一次，使用返回的同一份
+func (s *Service) snapshot() *runtimeConfig {
+	return s.current.Load()
+}
+
+// 当前生效的配置，返回的配置只读
+func (s *Service) config() *Config {
+	return &s.current.Load().config
+}
+
+// reloadResult 一次重新加载实际生效的配置变化
+type reloadResult struct {
+	Changes []configChange `json:"changes"`
+	// 新配置中修改了但需要重启才能生效的配置项
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// configChange 单个配置字段的变化，敏感字段的值已脱敏
+type configChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// 逐字段比较两份配置，按 Config 字段顺序返回有变化的字段。
+// 比较使用原始值，输出使用 maskSecrets 后的值，令牌修改后只显示为 "***"
+func diffConfigs(old, updated Config) []configChange {
+	changes := []configChange{}
+	rawOld, rawNew := reflect.ValueOf(old), reflect.ValueOf(updated)
+	maskedOld, maskedNew := reflect.ValueOf(maskSecrets(old)), reflect.ValueOf(maskSecrets(updated))
+	for i := 0; i < rawOld.NumField(); i++ {
+		if reflect.DeepEqual(rawOld.Field(i).Interface(), rawNew.Field(i).Interface()) {
+			continue
+		}
+		changes = append(changes, configChange{
+			Field: rawOld.Type().Field(i).Name,
+			Old:   maskedOld.Field(i).Interface(),
+			New:   maskedNew.Field(i).Interface(),
+		})
+	}
+	return changes
+}
+
+// 重新加载配置文件并应用到运行中的服务，事件缓存、静音和去重等运行状态保持不变，返回生效的配置变化。
+// 新配置无法解析、通知模板或通知渠道初始化失败时返回错误，继续使用原配置。
+// 调用时会等待正在进行的检查结束，检查间隔变化后需由调用方重置定时器
+func (s *Service) reloadConfig(path string) (reloadResult, error) {
+	var result reloadResult
+	if path == "-" {
+		return result, fmt.Errorf("从标准输入读取的配置不支持重新加载")
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		return result, err
+	}
+
+	var tmpl *template.Template
+	if config.TemplateFile != "" {
+		if tmpl, err = loadTemplates(config.TemplateFile, config.TimeFormat, config.ImpactLabels); err != nil {
+			return result, fmt.Errorf("加载通知模板失败: %v", err)
+		}
+	}
+
+	s.checkMutex.Lock()
+	defer s.checkMutex.Unlock()
+
+	old := *s.config()
+	for _, item := range restartOnlyKeys {
+		if item.value(config) != item.value(old) {
+			logWarnf("%s 的修改需要重启服务才能生效，本次重新加载保留原值: %s", item.key, item.value(old))
+			result.RestartRequired = append(result.RestartRequired, item.key)
+		}
+	}
+	config.StateFile = old.StateFile
+	config.DBPath = old.DBPath
+	config.HealthListenAddr = old.HealthListenAddr
+	config.LogFile = old.LogFile
+	config.LogFormat = old.LogFormat
+	config.LogMaxSizeMB = old.LogMaxSizeMB
+	config.NotifyRateLimit = old.NotifyRateLimit
+	config.NotifyQueueFile = old.NotifyQueueFile
+	config.NotifyQueueMaxAgeHours = old.NotifyQueueMaxAgeHours
+	config.NotifyDedupFile = old.NotifyDedupFile
+	config.NotifyDedupTTLMinutes = old.NotifyDedupTTLMinutes
+
+	// 通知渠道按新配置创建在新的 runtimeConfig 中，失败时原配置保持不变；
+	// 投递队列和去重记录复用已打开的实例（创建不依赖 s.mutex，持有 checkMutex 保证不会并发创建）
+	rt, err := newRuntimeConfig(config)
+	if err != nil {
+		return result, err
+	}
+	rt.templates = tmpl
+	notifiers, err := buildNotifiers(s, rt)
+	if err != nil {
+		return result, fmt.Errorf("初始化通知渠道失败: %v", err)
+	}
+	rt.notifiers = notifiers
+
+	s.mutex.Lock()
+	s.current.Store(rt)
+	if config.CheckIntervalMinutes != old.CheckIntervalMinutes || config.ActiveCheckIntervalMinutes != old.ActiveCheckIntervalMinutes {
+		s.pollInterval = 0
+		s.pollIntervalChangedAt = time.Time{}
+	}
+	s.mutex.Unlock()
+	setLogLevel(config.LogLevel)
+
+	result.Changes = diffConfigs(old, config)
+	for _, change := range result.Changes {
+		logInfof("配置项 %s 已修改: %v -> %v", change.Field, change.Old, change.New)
+	}
+	return result, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 发送过程中重新加载配置：正在进行的发送继续使用原配置完成，之后的发送使用新配置，
+// 投递队列复用已打开的实例。需要配合 go test -race 运行
+func TestReloadDuringSend(t *testing.T) {
+	first, second := newFakeDingtalk(t), newFakeDingtalk(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "env.config")
+	writeConfig := func(baseURL string) {
+		t.Helper()
+		content := baseTestConfig + "DINGTALK_BASE_URL=" + baseURL + "\nNOTIFY_QUEUE_FILE=" + filepath.Join(dir, "queue.json") + "\n"
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(first.URL)
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestService(t, "")
+	rt, err := newRuntimeConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.current.Store(rt)
+	buildTestNotifiers(t, s)
+	queue := s.notifyQueue
+	if queue == nil {
+		t.Fatal("未打开投递队列")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	first.hold()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.notify(ctx, Notification{Kind: notifyKindHealth, Title: "before reload", Content: "before reload"})
+	}()
+	select {
+	case <-first.entered:
+	case <-ctx.Done():
+		t.Fatal("通知未开始发送")
+	}
+
+	writeConfig(second.URL)
+	result, err := s.reloadConfig(path)
+	if err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if len(result.Changes) != 1 || result.Changes[0].Field != "DingtalkBaseURL" {
+		t.Errorf("配置变化 = %+v, 期望只有 DingtalkBaseURL", result.Changes)
+	}
+	first.release()
+	if err := <-done; err != nil {
+		t.Fatalf("重新加载前开始的发送失败: %v", err)
+	}
+
+	if err := s.notify(ctx, Notification{Kind: notifyKindHealth, Title: "after reload", Content: "after reload"}); err != nil {
+		t.Fatalf("重新加载后的发送失败: %v", err)
+	}
+	if got := first.received(); len(got) != 1 || got[0].Markdown.Title != "before reload" {
+		t.Errorf("原地址收到的消息 = %+v", got)
+	}
+	if got := second.received(); len(got) != 1 || got[0].Markdown.Title != "after reload" {
+		t.Errorf("新地址收到的消息 = %+v", got)
+	}
+	if s.notifyQueue != queue {
+		t.Error("重新加载后投递队列被重新打开")
+	}
+	dingtalk, ok := s.snapshot().notifiers[0].(*dingtalkNotifier)
+	if !ok || dingtalk.queue != queue {
+		t.Error("新的钉钉渠道没有复用原投递队列")
+	}
+}
+
+// 修改只在启动时生效的配置项时保留原值并提示需要重启
+func TestReloadKeepsRestartOnlyKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "env.config")
+	if err := ioutil.WriteFile(path, []byte(baseTestConfig+"NOTIFY_QUEUE_FILE="+filepath.Join(dir, "a.json")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestService(t, "")
+	rt, err := newRuntimeConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.current.Store(rt)
+	buildTestNotifiers(t, s)
+
+	if err := ioutil.WriteFile(path, []byte(baseTestConfig+"NOTIFY_QUEUE_FILE="+filepath.Join(dir, "b.json")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.reloadConfig(path)
+	if err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if len(result.RestartRequired) != 1 || result.RestartRequired[0] != "NOTIFY_QUEUE_FILE" {
+		t.Errorf("需要重启的配置项 = %v, 期望 [NOTIFY_QUEUE_FILE]", result.RestartRequired)
+	}
+	if got := s.config().NotifyQueueFile; got != filepath.Join(dir, "a.json") {
+		t.Errorf("NOTIFY_QUEUE_FILE = %s, 期望保留原值", got)
+	}
+}
+
+// POST /reload 与主循环的每日报告发送同时进行，需要配合 go test -race 运行
+func TestHTTPReloadDuringDailyReport(t *testing.T) {
+	page := newFakeStatusPage(t)
+	page.setIncidents(t, "")
+	dingtalk := newFakeDingtalk(t)
+	path := filepath.Join(t.TempDir(), "env.config")
+	writeConfig := func(maxIncidents string) {
+		t.Helper()
+		content := baseTestConfig + "STATUS_PAGE_URL=" + page.URL + "\nDINGTALK_BASE_URL=" + dingtalk.URL +
+			"\nDAILY_REPORT_UTC_HOUR=12\nSEND_STARTUP_NOTIFICATION=false\nCHECK_TRIGGER_TOKEN=secret\nMAX_INCIDENTS=" + maxIncidents + "\n"
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig("10")
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestService(t, "")
+	s.configPath = path
+	rt, err := newRuntimeConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.current.Store(rt)
+	buildTestNotifiers(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dingtalk.hold()
+	done := make(chan error, 1)
+	go func() { done <- s.runTick(ctx, true) }()
+	select {
+	case <-dingtalk.entered:
+	case <-ctx.Done():
+		t.Fatal("每日报告未开始发送")
+	}
+
+	writeConfig("20")
+	req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.handleReload(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /reload 返回 %d: %s", rec.Code, rec.Body.String())
+	}
+	dingtalk.release()
+	if err := <-done; err != nil {
+		t.Fatalf("runTick 返回错误: %v", err)
+	}
+
+	if got := s.config().MaxIncidents; got != 20 {
+		t.Errorf("MAX_INCIDENTS = %d, 期望重新加载后的 20", got)
+	}
+	if got := dingtalk.received(); len(got) != 1 || got[0].Markdown.Title != "Cloudflare 每日状态报告" {
+		t.Errorf("钉钉收到的消息 = %+v, 期望一条每日报告", got)
+	}
+	select {
+	case <-s.reloaded:
+	default:
+		t.Error("重新加载后没有通知主循环重置定时器")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 检查长时间未解决的事件，超过 LONG_INCIDENT_THRESHOLD_MINUTES 时发送一次提醒。
+// 静音中的事件不提醒，静音到期后若仍未解决会再提醒
+func (s *Service) checkLongIncidents(ctx context.Context) {
+	if s.config().LongIncidentThresholdMinutes <= 0 {
+		return
+	}
+	notification := s.detectLongIncidents(s.Now())
+	if notification == nil {
+		return
+	}
+	if err := s.notify(ctx, *notification); err != nil {
+		logEvent("error", "notify", logFields{"kind": notification.Kind, "error": err.Error()}, "发送长时间未解决提醒失败: %v", err)
+	}
+}
+
+// 在持锁状态下找出新超过阈值的未解决事件，返回需要发送的提醒
+func (s *Service) detectLongIncidents(now time.Time) *Notification {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.longIncidentAlerted == nil {
+		s.longIncidentAlerted = make(map[string]bool)
+	}
+	threshold := time.Duration(s.config().LongIncidentThresholdMinutes) * time.Minute
+
+	var overdue []Incident
+	for id, incident := range s.lastIncidents {
+		if incident.isResolved() {
+			delete(s.longIncidentAlerted, id)
+			continue
+		}
+		if s.longIncidentAlerted[id] || now.Sub(incident.CreatedAt) < threshold {
+			continue
+		}
+		if s.isMuted(incident, changeTypeUpdate) {
+			continue
+		}
+		s.longIncidentAlerted[id] = true
+		overdue = append(overdue, incident)
+	}
+	// 清理已不在缓存中的事件
+	for id := range s.longIncidentAlerted {
+		if _, ok := s.lastIncidents[id]; !ok {
+			delete(s.longIncidentAlerted, id)
+		}
+	}
+	if len(overdue) == 0 {
+		return nil
+	}
+
+	sort.Slice(overdue, func(i, j int) bool {
+		return overdue[i].CreatedAt.Before(overdue[j].CreatedAt)
+	})
+
+	var content strings.Builder
+	content.WriteString("# Cloudflare 事件长时间未解决\n\n")
+	content.WriteString(notificationHeader(s.statusVersion, s.formatTime(s.Now())))
+	content.WriteString(fmt.Sprintf("以下事件已超过 %d 分钟仍未解决，可能需要升级处理:\n\n", s.config().LongIncidentThresholdMinutes))
+	for _, incident := range overdue {
+		logEvent("warn", "detector", logFields{"incident_id": incident.ID, "change": "long_running"},
+			"事件长时间未解决 - ID: %s, 名称: %s, 已持续 %v", incident.ID, incident.Name, now.Sub(incident.CreatedAt).Round(time.Minute))
+		content.WriteString(fmt.Sprintf("## 长时间未解决（已持续 %.0f 分钟）\n", now.Sub(incident.CreatedAt).Minutes()))
+		content.WriteString(s.formatIncidentDetails(incident, s.displayUpdates(incident, nil)))
+	}
+	content.WriteString("\n---\n")
+	content.WriteString(s.notificationFooter(nil))
+
+	logInfof("发现 %d 个长时间未解决的事件，准备发送提醒", len(overdue))
+	return &Notification{
+		Kind:    notifyKindLongIncident,
+		Title:   "Cloudflare 事件长时间未解决",
+		Content: content.String(),
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// incidentDoc 事件详情的结构化表示，与具体通知渠道的 Markdown 方言无关。
+// 各渠道通过 incidentRenderer 将其渲染为自己支持的格式
+type incidentDoc struct {
+	Name     string
+	Status   string
+	Impact   string
+	Colorize bool       // 是否在标题前添加影响程度和状态标记
+	Fields   []docField // 事件属性，按顺序展示
+	// 受影响的组件，Label 为组件名称，Value 为组件状态，为空时不展示
+	Components []docField
+	// 更新列表的标题，如 "更新历史" 或 "最新更新（共 N 条）"，没有更新时为空
+	UpdatesTitle string
+	Updates      []docUpdate
+	// 超过 MAX_UPDATES_IN_DETAIL 而未展示的较早更新条数
+	OmittedUpdates int
+	Link           string
+	// 影响程度标记中展示的名称，配置 IMPACT_LABELS 时为对应名称
+	ImpactLabel string
+}
+
+// docField 事件属性
+type docField struct {
+	Label string
+	Value string
+}
+
+// docUpdate 事件更新记录，Body 为原始内容，由渲染器按各自的方言清理和转义
+type docUpdate struct {
+	Time   string
+	Status string
+	Body   string
+}
+
+// incidentRenderer 将事件详情渲染为某个通知渠道的消息格式
+type incidentRenderer interface {
+	renderIncident(doc incidentDoc) string
+}
+
+// 构建事件详情的结构化表示，updates 为需要展示的更新记录
+func (s *Service) incidentDoc(incident Incident, updates []Update) incidentDoc {
+	doc := incidentDoc{
+		Name:     incident.Name,
+		Status:   incident.Status,
+		Impact:   incident.Impact,
+		Colorize: s.config().ColorizeOutput,
+		Fields: []docField{
+			{"ID", s.displayIncidentID(incident.ID)},
+			{"状态", incident.Status},
+			{"影响程度", s.impactLabel(incident.Impact)},
+			{"创建时间", s.formatTime(incident.CreatedAt)},
+			{"更新时间", s.formatTime(incident.UpdatedAt)},
+		},
+		Link:        s.incidentLink(incident),
+		ImpactLabel: s.impactBadgeLabel(incident.Impact),
+	}
+	if !incident.MonitoringAt.IsZero() {
+		doc.Fields = append(doc.Fields, docField{"监控开始时间", s.formatTime(incident.MonitoringAt)})
+	}
+	if resolvedAt := incident.resolvedTime(); !resolvedAt.IsZero() {
+		doc.Fields = append(doc.Fields, docField{"解决时间", s.formatTime(resolvedAt)})
+	}
+
+	for _, component := range incident.Components {
+		doc.Components = append(doc.Components, docField{component.Name, componentStatusName(component.Status)})
+	}
+
+	if len(updates) > 0 {
+		if len(updates) < len(incident.IncidentUpdates) {
+			doc.UpdatesTitle = fmt.Sprintf("最新更新（共 %d 条）", len(incident.IncidentUpdates))
+		} else {
+			doc.UpdatesTitle = "更新历史"
+		}
+		updates, doc.OmittedUpdates = limitUpdates(updates, s.config().MaxUpdatesInDetail)
+		for _, update := range updates {
+			doc.Updates = append(doc.Updates, docUpdate{
+				Time:   s.formatTime(update.CreatedAt),
+				Status: update.Status,
+				Body:   update.Body,
+			})
+		}
+	}
+	return doc
+}
+
+// 短 ID 展示的字符数
+const shortIncidentIDLength = 8
+
+// 事件详情中展示的事件 ID。关闭 SHOW_FULL_INCIDENT_ID 时只展示前 8 个字符，完整 ID 仍包含在事件链接中
+func (s *Service) displayIncidentID(id string) string {
+	if s.config().ShowFullIncidentID || len(id) <= shortIncidentIDLength {
+		return id
+	}
+	return id[:shortIncidentIDLength]
+}
+
+// 只保留最近的 limit 条更新，按时间从新到旧排列，返回被省略的条数。limit 为 0 时不限制
+func limitUpdates(updates []Update, limit int) ([]Update, int) {
+	if limit <= 0 || len(updates) <= limit {
+		return updates, 0
+	}
+	sorted := make([]Update, len(updates))
+	copy(sorted, updates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+	return sorted[:limit], len(updates) - limit
+}
+
+// dingtalkRenderer 渲染为钉钉 Markdown，也是其他渠道目前共用的默认格式
+type dingtalkRenderer struct{}
+
+func (dingtalkRenderer) renderIncident(doc incidentDoc) string {
+	var details strings.Builder
+	if doc.Colorize {
+		badge := impactBadge(doc.Status, doc.Impact, doc.ImpactLabel)
+		details.WriteString(fmt.Sprintf("### %s事件: %s\n", badge, doc.Name))
+	} else {
+		details.WriteString(fmt.Sprintf("### 事件: %s\n", doc.Name))
+	}
+	for _, field := range doc.Fields {
+		details.WriteString(fmt.Sprintf("- %s: %s\n", field.Label, field.Value))
+	}
+
+	if len(doc.Components) > 0 {
+		details.WriteString("\n受影响组件:\n")
+		for _, component := range doc.Components {
+			details.WriteString(fmt.Sprintf("- %s: %s\n", component.Label, component.Value))
+		}
+	}
+
+	if doc.UpdatesTitle != "" {
+		details.WriteString(fmt.Sprintf("\n%s:\n", doc.UpdatesTitle))
+		for _, update := range doc.Updates {
+			details.WriteString(fmt.Sprintf("- %s [%s]: %s\n", update.Time, update.Status, sanitizeUpdateBody(update.Body)))
+		}
+		if doc.OmittedUpdates > 0 {
+			details.WriteString(fmt.Sprintf("- ... 还有 %d 条更新\n", doc.OmittedUpdates))
+		}
+	}
+
+	details.WriteString(fmt.Sprintf("\n事件链接: %s\n", doc.Link))
+	details.WriteString("\n")
+	return details.String()
+}
+
+// 通知中时间的默认格式
+const defaultTimeFormat = "2006-01-02 15:04:05"
+
+// 按 layout 格式化时间的函数，零值时间格式化为空字符串
+func timeFormatter(layout string) func(time.Time) string {
+	return func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(layout)
+	}
+}
+
+// 按 TIME_FORMAT 格式化通知中的时间，所有通知内容中的时间都通过它输出
+func (s *Service) formatTime(t time.Time) string {
+	return timeFormatter(s.config().TimeFormat)(t)
+}
+
+// 校验 TIME_FORMAT：用示例时间试格式化，结果与布局相同说明其中没有可识别的时间元素
+func validateTimeFormat(layout string) error {
+	sample := time.Date(2024, time.March, 5, 14, 7, 9, 0, time.UTC)
+	if strings.TrimSpace(layout) == "" || sample.Format(layout) == layout {
+		return fmt.Errorf("TIME_FORMAT 必须是有效的 Go 时间格式，如 %s", defaultTimeFormat)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stdoutNotifier 回放模式使用的通知渠道，把通知内容输出到 w 而不是真正发送
+type stdoutNotifier struct {
+	w     io.Writer
+	mutex sync.Mutex
+	count int
+}
+
+func (p *stdoutNotifier) Name() string {
+	return "stdout"
+}
+
+func (p *stdoutNotifier) Send(ctx context.Context, n Notification) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.count++
+	fmt.Fprintf(p.w, "===== 通知 #%d [%s] %s =====\n", p.count, n.Kind, n.Title)
+	for _, event := range n.Events {
+		fmt.Fprintf(p.w, "- %s: %s (%s, %s)\n", event.ChangeType, event.Incident.Name, event.Incident.ID, event.Incident.Status)
+	}
+	fmt.Fprintf(p.w, "\n%s\n", strings.TrimRight(n.Content, "\n"))
+	return nil
+}
+
+// replaySnapshot 回放目录中的一个事件快照
+type replaySnapshot struct {
+	path      string
+	takenAt   time.Time
+	incidents []Incident
+}
+
+// 读取一个快照文件，支持调试转储的事件数组和状态页接口的 {"incidents": [...]} 两种格式。
+// 文件名符合调试转储格式时以其中的时间戳作为快照时间，否则使用文件的修改时间
+func readReplaySnapshot(path string) (replaySnapshot, error) {
+	snapshot := replaySnapshot{path: path}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	if err := json.Unmarshal(data, &snapshot.incidents); err != nil {
+		var response Response
+		if err := json.Unmarshal(data, &response); err != nil {
+			return snapshot, fmt.Errorf("JSON 解析失败: %v", err)
+		}
+		snapshot.incidents = response.Incidents
+	}
+	snapshot.incidents = validateIncidents(path, snapshot.incidents)
+
+	name := filepath.Base(path)
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, debugDumpPrefix), ".json")
+	if takenAt, err := time.Parse("20060102T150405.000Z", stamp); err == nil && strings.HasPrefix(name, debugDumpPrefix) {
+		snapshot.takenAt = takenAt
+		return snapshot, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.takenAt = info.ModTime()
+	return snapshot, nil
+}
+
+// -replay 模式：按文件名顺序读取目录中的事件快照，依次交给变化检测，通知输出到 out 而不发送。
+// 每个快照处理时当前时间固定为快照时间，结果可重复；不读写状态文件和历史存储
+func (s *Service) runReplay(dir string, out io.Writer) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("读取回放目录失败: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("回放目录中没有 JSON 文件: %s", dir)
+	}
+	sort.Strings(names)
+
+	printer := &stdoutNotifier{w: out}
+	rt := *s.snapshot()
+	rt.notifiers = []Notifier{printer}
+	// 回放时直接输出全部通知，不经过人工批准
+	rt.config.ApprovalMode = false
+	s.current.Store(&rt)
+	s.history = nil
+	ctx := context.Background()
+	for i, name := range names {
+		snapshot, err := readReplaySnapshot(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("读取快照 %s 失败: %v", name, err)
+		}
+		snapshot.incidents = dedupeIncidents(snapshot.incidents, s.config().DuplicateIncidentPolicy)
+		takenAt := snapshot.takenAt
+		s.Now = func() time.Time { return takenAt }
+		logInfof("回放快照 %d/%d: %s（%s，%d 个事件）", i+1, len(names), name,
+			takenAt.Format("2006-01-02 15:04:05"), len(snapshot.incidents))
+		for j := range snapshot.incidents {
+			if snapshot.incidents[j].Page == "" {
+				snapshot.incidents[j].Page = s.config().StatusPageURL
+			}
+		}
+		changes := s.checkForChanges(ctx, snapshot.incidents)
+		logInfof("快照 %s 检测到 %d 个变化", name, changes)
+	}
+	logInfof("回放完成，共 %d 个快照，输出 %d 条通知", len(names), printer.count)
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 未匹配任何关键词路由规则的变化在钉钉中使用的路由名称，按影响程度选择机器人
+const dingtalkRouteDefault = "default"
+
+// dingtalkRoute 一条关键词路由规则：事件名称或最新更新内容包含关键词时发送到对应的钉钉机器人
+type dingtalkRoute struct {
+	Keyword string // 小写的关键词
+	Token   string
+	Secret  string
+}
+
+// 路由规则对应的钉钉机器人名称，用于日志和投递队列
+func (r dingtalkRoute) name() string {
+	return "route:" + r.Keyword
+}
+
+// 解析 "关键词=access_token:secret; 关键词=access_token:secret" 格式的钉钉关键词路由，
+// 使用自定义关键词安全设置时可以省略 secret
+func parseDingtalkRoutes(value string) ([]dingtalkRoute, error) {
+	var routes []dingtalkRoute
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		keyword := strings.ToLower(strings.TrimSpace(parts[0]))
+		if len(parts) != 2 || keyword == "" {
+			return nil, fmt.Errorf("DINGTALK_ROUTES 格式无效，应为 \"关键词=access_token:secret; 关键词=access_token:secret\": %s", entry)
+		}
+		value := strings.TrimSpace(parts[1])
+		var route dingtalkRoute
+		if token, rest, ok := extractAccessToken(value); ok {
+			// 误填了完整的 Webhook 地址，地址中包含冒号，secret 只能出现在 access_token 之后
+			logWarnf("DINGTALK_ROUTES 中关键词 %s 配置的是完整的 Webhook 地址，已自动提取其中的 access_token", keyword)
+			route = dingtalkRoute{Keyword: keyword, Token: token}
+			if strings.HasPrefix(rest, ":") {
+				route.Secret = strings.TrimSpace(rest[1:])
+			}
+		} else {
+			credentials := strings.SplitN(value, ":", 2)
+			route = dingtalkRoute{Keyword: keyword, Token: strings.TrimSpace(credentials[0])}
+			if len(credentials) == 2 {
+				route.Secret = strings.TrimSpace(credentials[1])
+			}
+		}
+		if route.Token == "" {
+			return nil, fmt.Errorf("DINGTALK_ROUTES 中关键词 %s 的 access_token 不能为空", keyword)
+		}
+		if !dingtalkTokenPattern.MatchString(route.Token) {
+			return nil, fmt.Errorf("DINGTALK_ROUTES 中关键词 %s 的 access_token 无效（应只包含字母和数字）: %s", keyword, route.Token)
+		}
+		if seen[keyword] {
+			return nil, fmt.Errorf("DINGTALK_ROUTES 中关键词 %s 重复", keyword)
+		}
+		seen[keyword] = true
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// 按关键词路由规则将变化分组。匹配多条规则的变化会出现在每个匹配的分组中，
+// 未匹配任何规则的变化返回在 unmatched 中
+func (s *Service) routeChanges(changes []incidentChange) (routed map[string][]incidentChange, unmatched []incidentChange) {
+	routed = make(map[string][]incidentChange)
+	for _, change := range changes {
+		matched := false
+		for _, route := range s.config().DingtalkRoutes {
+			if _, ok := matchRegionKeyword(change.Event.Incident, []string{route.Keyword}); ok {
+				routed[route.name()] = append(routed[route.name()], change)
+				matched = true
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, change)
+		}
+	}
+	return routed, unmatched
+}
+
+// 生成变更通知并按关键词路由拆分，调用方需持有锁。未配置 DINGTALK_ROUTES 时只生成一条通知；
+// 配置后其他渠道仍收到包含全部变化的通知，钉钉改为按路由分组分别发送
+func (s *Service) buildRoutedNotifications(changes []incidentChange, titleFor func([]incidentChange) string) []Notification {
+	build := func(group []incidentChange) Notification {
+		title := titleFor(group)
+		return s.buildChangeNotification(title, "# "+title+"\n\n", group)
+	}
+	if len(s.config().DingtalkRoutes) == 0 {
+		return []Notification{build(changes)}
+	}
+
+	routed, unmatched := s.routeChanges(changes)
+	full := build(changes)
+	full.SkipDingtalk = true
+	notifications := []Notification{full}
+	for _, route := range s.config().DingtalkRoutes {
+		group := routed[route.name()]
+		if len(group) == 0 {
+			continue
+		}
+		logInfof("%d 个变化匹配钉钉路由关键词: %s", len(group), route.Keyword)
+		n := build(group)
+		n.Route = route.name()
+		notifications = append(notifications, n)
+	}
+	if len(unmatched) > 0 {
+		n := build(unmatched)
+		n.Route = dingtalkRouteDefault
+		notifications = append(notifications, n)
+	}
+	return notifications
+}
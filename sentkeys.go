@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sentKeyStore 记录最近已发送通知的去重键，持久化到磁盘，多个实例可共用同一个文件。
+// 每次检查都重新读取文件，以便看到其他实例写入的记录；读取、判断和写入期间持有
+// path + ".lock" 上的排他文件锁，保证多个实例不会同时认领同一个去重键
+type sentKeyStore struct {
+	path  string
+	ttl   time.Duration
+	now   func() time.Time
+	mutex sync.Mutex
+}
+
+func newSentKeyStore(path string, ttl time.Duration, now func() time.Time) *sentKeyStore {
+	return &sentKeyStore{path: path, ttl: ttl, now: now}
+}
+
+// 读取未过期的记录，文件不存在或内容损坏时视为空
+func (k *sentKeyStore) load(now time.Time) map[string]time.Time {
+	keys := make(map[string]time.Time)
+	data, err := ioutil.ReadFile(k.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logErrorf("读取通知去重记录失败: %v", err)
+		}
+		return keys
+	}
+	if err := json.Unmarshal(data, &keys); err != nil {
+		logErrorf("解析通知去重记录失败，将重新记录: %v", err)
+		return make(map[string]time.Time)
+	}
+	for key, sentAt := range keys {
+		if now.Sub(sentAt) > k.ttl {
+			delete(keys, key)
+		}
+	}
+	return keys
+}
+
+// 在文件锁内读取记录并交给 fn 修改，fn 返回 true 时写回文件。
+// 无法加锁时记录日志后仍继续处理，退化为尽力而为的去重
+func (k *sentKeyStore) update(fn func(keys map[string]time.Time, now time.Time) bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	unlock, err := lockFile(k.path + ".lock")
+	if err != nil {
+		logErrorf("锁定通知去重记录失败，多个实例可能重复发送: %v", err)
+	} else {
+		defer unlock()
+	}
+
+	now := k.now()
+	keys := k.load(now)
+	if !fn(keys, now) {
+		return
+	}
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(k.path, data, 0600)
+	}
+	if err != nil {
+		logErrorf("保存通知去重记录失败: %v", err)
+	}
+}
+
+// 认领去重键：TTL 内已发送过或已被其他实例认领时返回 false，否则立即记录并返回 true。
+// 发送失败时调用方应通过 Release 释放，以便之后重试
+func (k *sentKeyStore) Claim(key string) bool {
+	claimed := false
+	k.update(func(keys map[string]time.Time, now time.Time) bool {
+		if _, ok := keys[key]; ok {
+			return false
+		}
+		keys[key] = now
+		claimed = true
+		return true
+	})
+	return claimed
+}
+
+// 释放认领但未成功发送的去重键
+func (k *sentKeyStore) Release(key string) {
+	k.update(func(keys map[string]time.Time, now time.Time) bool {
+		if _, ok := keys[key]; !ok {
+			return false
+		}
+		delete(keys, key)
+		return true
+	})
+}
+
+// 计算变更通知的去重键：由各事件的 ID、更新时间和变化类型以及路由名称确定，
+// 同一变化无论由哪个实例检测到都得到相同的键
+func notificationDedupKey(route string, events []IncidentEvent) string {
+	parts := make([]string, 0, len(events))
+	for _, event := range events {
+		parts = append(parts, fmt.Sprintf("%s|%s|%s", event.Incident.ID,
+			event.Incident.UpdatedAt.UTC().Format(time.RFC3339Nano), event.ChangeType))
+	}
+	sort.Strings(parts)
+	hash := sha256.New()
+	hash.Write([]byte(route))
+	for _, part := range parts {
+		hash.Write([]byte("\n" + part))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
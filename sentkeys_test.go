@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 同一变化（事件 ID、更新时间和变化类型相同）在 TTL 内只发送一次，记录过期后再次发送
+func TestSentKeyStoreTTL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sent-keys.json")
+	clock := &testClock{now: testNow}
+	store := newSentKeyStore(path, time.Hour, clock.Now)
+	incident := testIncident("inc1", "investigating", "major", time.Hour)
+	key := notificationDedupKey("", []IncidentEvent{{Incident: incident, ChangeType: changeTypeNew}})
+
+	if !store.Claim(key) {
+		t.Fatal("尚未记录的去重键认领失败")
+	}
+	if store.Claim(key) {
+		t.Fatal("TTL 内已记录的去重键没有被跳过")
+	}
+	// 另一个实例共用同一个文件时同样跳过
+	if newSentKeyStore(path, time.Hour, clock.Now).Claim(key) {
+		t.Error("共用去重文件的实例没有看到已记录的去重键")
+	}
+
+	clock.set(testNow.Add(2 * time.Hour))
+	if !store.Claim(key) {
+		t.Error("超过 TTL 的去重键仍被跳过")
+	}
+
+	// 发送失败释放后可以再次认领
+	store.Release(key)
+	if !store.Claim(key) {
+		t.Error("释放后的去重键认领失败")
+	}
+}
+
+// 多个实例同时认领同一个去重键时只有一个成功
+func TestSentKeyStoreConcurrentClaim(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sent-keys.json")
+	key := notificationDedupKey("", []IncidentEvent{{Incident: testIncident("inc1", "investigating", "major", time.Hour), ChangeType: changeTypeNew}})
+
+	var claimed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		store := newSentKeyStore(path, time.Hour, time.Now)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if store.Claim(key) {
+				atomic.AddInt32(&claimed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if claimed != 1 {
+		t.Errorf("%d 个实例认领成功，期望 1 个", claimed)
+	}
+}
+
+func TestNotificationDedupKey(t *testing.T) {
+	incident := testIncident("inc1", "investigating", "major", time.Hour)
+	other := testIncident("inc2", "identified", "minor", time.Hour)
+	key := notificationDedupKey("", []IncidentEvent{{Incident: incident, ChangeType: changeTypeNew}, {Incident: other, ChangeType: changeTypeUpdate}})
+
+	if got := notificationDedupKey("", []IncidentEvent{{Incident: other, ChangeType: changeTypeUpdate}, {Incident: incident, ChangeType: changeTypeNew}}); got != key {
+		t.Error("事件顺序不同时去重键不同")
+	}
+	updated := incident
+	updated.UpdatedAt = incident.UpdatedAt.Add(time.Minute)
+	if got := notificationDedupKey("", []IncidentEvent{{Incident: updated, ChangeType: changeTypeNew}, {Incident: other, ChangeType: changeTypeUpdate}}); got == key {
+		t.Error("更新时间不同时去重键相同")
+	}
+	if got := notificationDedupKey("", []IncidentEvent{{Incident: incident, ChangeType: changeTypeUpdate}, {Incident: other, ChangeType: changeTypeUpdate}}); got == key {
+		t.Error("变化类型不同时去重键相同")
+	}
+	if got := notificationDedupKey("db", []IncidentEvent{{Incident: incident, ChangeType: changeTypeNew}, {Incident: other, ChangeType: changeTypeUpdate}}); got == key {
+		t.Error("路由不同时去重键相同")
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 启动健康检查和查询接口服务，监听失败时返回错误
+func (s *Service) startHTTPServer(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/incidents", s.handleIncidents)
+	mux.HandleFunc("/history", s.handleHistory)
+	mux.HandleFunc("/check", s.handleCheck)
+	mux.HandleFunc("/mute", s.handleMute)
+	mux.HandleFunc("/reload", s.handleReload)
+	mux.HandleFunc("/approve", s.handleApprove)
+	mux.HandleFunc("/reject", s.handleReject)
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logErrorf("HTTP 服务异常退出: %v", err)
+		}
+	}()
+	logInfof("HTTP 服务已启动，监听地址: %s", listener.Addr())
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logErrorf("写入 HTTP 响应失败: %v", err)
+	}
+}
+
+// GET /health 返回服务运行状态
+func (s *Service) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	status := map[string]interface{}{
+		"status":               "ok",
+		"cached_incidents":     len(s.lastIncidents),
+		"consecutive_failures": s.consecutiveFailures,
+		"degraded":             s.degradedAlertSent,
+	}
+	if !s.lastHeartbeat.IsZero() {
+		status["last_heartbeat"] = s.lastHeartbeat.Format(time.RFC3339)
+	}
+	if !s.lastCheckTime.IsZero() {
+		status["last_check"] = s.lastCheckTime.Format(time.RFC3339)
+	}
+	if !s.lastReportTime.IsZero() {
+		status["last_report"] = s.lastReportTime.Format(time.RFC3339)
+	}
+	s.mutex.RUnlock()
+	if breaker := s.snapshot().dingtalkBreaker; breaker != nil {
+		status["dingtalk_circuit"] = breaker.Snapshot()
+	}
+
+	if status["degraded"] == true {
+		status["status"] = "degraded"
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// GET /incidents 返回缓存中的事件，按创建时间倒序。
+// 支持 ?status=<状态> 精确过滤和 ?impact=<影响程度> 最低影响程度过滤
+func (s *Service) handleIncidents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "只支持 GET 请求"})
+		return
+	}
+
+	statusFilter := r.URL.Query().Get("status")
+	impactFilter := r.URL.Query().Get("impact")
+	minRank, ok := impactRank[impactFilter]
+	if impactFilter != "" && !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "未知的影响程度: " + impactFilter})
+		return
+	}
+
+	s.mutex.RLock()
+	incidents := make([]Incident, 0, len(s.lastIncidents))
+	for _, incident := range s.lastIncidents {
+		if statusFilter != "" && incident.Status != statusFilter {
+			continue
+		}
+		if impactFilter != "" && impactRank[incident.Impact] < minRank {
+			continue
+		}
+		incidents = append(incidents, incident)
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].CreatedAt.After(incidents[j].CreatedAt)
+	})
+	writeJSON(w, http.StatusOK, incidents)
+}
+
+// 解析日期参数，支持 2006-01-02 和 RFC3339 两种格式
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GET /history?from=<日期>&to=<日期> 查询历史存储中指定时间范围的事件，默认最近 7 天
+func (s *Service) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "未启用事件历史存储（DB_PATH）"})
+		return
+	}
+
+	to := s.Now()
+	from := to.AddDate(0, 0, -7)
+	var err error
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = parseDateParam(value); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from 参数格式无效"})
+			return
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = parseDateParam(value); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to 参数格式无效"})
+			return
+		}
+	}
+
+	incidents, err := s.history.QueryIncidents(from, to)
+	if err != nil {
+		logErrorf("查询事件历史失败: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "查询事件历史失败"})
+		return
+	}
+	if incidents == nil {
+		incidents = []Incident{}
+	}
+	writeJSON(w, http.StatusOK, incidents)
+}
+
+// 校验管理类接口的请求方法和 Bearer 令牌，校验失败时已写入错误响应
+func (s *Service) authorizePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "只支持 POST 请求"})
+		return false
+	}
+	if s.config().CheckTriggerToken == "" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "未配置 CHECK_TRIGGER_TOKEN，管理接口已禁用"})
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.config().CheckTriggerToken)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "令牌无效"})
+		return false
+	}
+	return true
+}
+
+// POST /check 立即执行一次检查，需要携带 Authorization: Bearer <CHECK_TRIGGER_TOKEN>。
+// 与定时检查通过 checkMutex 串行执行，返回本次检测到的变化数量
+func (s *Service) handleCheck(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizePost(w, r) {
+		return
+	}
+
+	logInfof("收到手动触发的检查请求，来源: %s", r.RemoteAddr)
+	ctx, cancel := s.tickContext()
+	defer cancel()
+	changes, err := s.fetchAndProcessIncidents(ctx)
+	if err != nil {
+		logEvent("error", "scheduler", logFields{"error": err.Error(), "trigger": "manual"}, "手动触发的检查失败: %v", err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"changes": changes})
+}
+
+// POST /mute?id=<事件ID>&minutes=<分钟数> 在指定时间内静音某个事件的更新通知，
+// 事件解决时仍会通知。minutes=0 表示取消静音
+func (s *Service) handleMute(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizePost(w, r) {
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "缺少 id 参数"})
+		return
+	}
+	minutes, err := strconv.Atoi(r.URL.Query().Get("minutes"))
+	if err != nil || minutes < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "minutes 必须是非负整数"})
+		return
+	}
+
+	s.mutex.Lock()
+	if s.mutedUntil == nil {
+		s.mutedUntil = make(map[string]time.Time)
+	}
+	var until time.Time
+	if minutes == 0 {
+		delete(s.mutedUntil, id)
+	} else {
+		until = s.Now().Add(time.Duration(minutes) * time.Minute)
+		s.mutedUntil[id] = until
+	}
+	s.mutex.Unlock()
+
+	if minutes == 0 {
+		logInfof("已取消事件静音 - ID: %s", id)
+		writeJSON(w, http.StatusOK, map[string]string{"id": id, "muted_until": ""})
+		return
+	}
+	logInfof("已静音事件 - ID: %s, 截止时间: %s", id, until.Format("2006-01-02 15:04:05"))
+	writeJSON(w, http.StatusOK, map[string]string{"id": id, "muted_until": until.Format(time.RFC3339)})
+}
+
+// POST /reload 重新读取配置文件并应用，与 SIGHUP 的处理相同，通过 reloadConfig 发布新的配置快照，
+// 可以与主循环的检查和每日报告发送同时进行。
+// 成功时返回生效的配置变化（敏感字段已脱敏），新配置无效时返回 422 并继续使用原配置
+func (s *Service) handleReload(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizePost(w, r) {
+		return
+	}
+
+	logInfof("收到 HTTP 重新加载配置请求，来源: %s，配置文件: %s", r.RemoteAddr, s.configPath)
+	result, err := s.reloadConfig(s.configPath)
+	if err != nil {
+		logErrorf("重新加载配置失败，继续使用原配置: %v", err)
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	select {
+	case s.reloaded <- struct{}{}:
+	default:
+	}
+	logInfof("配置已通过 HTTP 重新加载，%d 个配置项有变化", len(result.Changes))
+	writeJSON(w, http.StatusOK, result)
+}
+
+// POST /approve?id=<批准ID> 批准 APPROVAL_MODE 下暂存的通知并立即发送
+func (s *Service) handleApprove(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizePost(w, r) {
+		return
+	}
+	id := r.URL.Query().Get("id")
+	pending, ok := s.approvals.Take(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "待批准通知不存在或已处理: " + id})
+		return
+	}
+
+	logInfof("通知已被批准 - ID: %s, 来源: %s, 标题: %s", id, r.RemoteAddr, pending.Notification.Title)
+	ctx, cancel := s.tickContext()
+	defer cancel()
+	if err := s.releaseApproval(ctx, pending); err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"id": id, "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": id, "result": "sent"})
+}
+
+// POST /reject?id=<批准ID> 拒绝 APPROVAL_MODE 下暂存的通知，通知不会发送
+func (s *Service) handleReject(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizePost(w, r) {
+		return
+	}
+	id := r.URL.Query().Get("id")
+	pending, ok := s.approvals.Take(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "待批准通知不存在或已处理: " + id})
+		return
+	}
+	logEvent("info", "approval", logFields{"approval_id": id, "title": pending.Notification.Title},
+		"通知已被拒绝，不会发送 - ID: %s, 来源: %s", id, r.RemoteAddr)
+	writeJSON(w, http.StatusOK, map[string]string{"id": id, "result": "rejected"})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// GET /health 读取最近检查和每日报告时间的同时主循环执行检查，需要配合 go test -race 运行
+func TestHealthDuringTicks(t *testing.T) {
+	page := newFakeStatusPage(t)
+	page.setIncidents(t, "", testIncident("inc1", "investigating", "minor", time.Hour))
+	s := newTestService(t, "STATUS_PAGE_URL="+page.URL+"\nDAILY_REPORT_UTC_HOUR=12\n")
+	recorder := &recordingNotifier{}
+	setTestNotifiers(s, recorder)
+
+	ctx := context.Background()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				rec := httptest.NewRecorder()
+				s.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+				if rec.Code != http.StatusOK {
+					t.Errorf("GET /health 返回 %d", rec.Code)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		if err := s.runTick(ctx, true); err != nil {
+			t.Fatalf("第 %d 轮检查失败: %v", i+1, err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	rec := httptest.NewRecorder()
+	s.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var status map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	want := testNow.Format(time.RFC3339)
+	if status["last_check"] != want || status["last_report"] != want {
+		t.Errorf("last_check = %v, last_report = %v, 期望 %s", status["last_check"], status["last_report"], want)
+	}
+	reports := 0
+	for _, n := range recorder.notifications() {
+		if n.Kind == notifyKindDailyReport {
+			reports++
+		}
+	}
+	if reports != 1 {
+		t.Errorf("同一发送时间发送了 %d 次每日报告，期望 1 次", reports)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 测试使用的固定时钟
+var testNow = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+// 按 baseTestConfig 加 extra 中的配置项创建服务，时钟固定为 testNow
+func newTestService(t *testing.T, extra string) *Service {
+	t.Helper()
+	config, err := loadTestConfig(t, baseTestConfig+extra)
+	if err != nil {
+		t.Fatalf("loadConfig 返回错误: %v", err)
+	}
+	s := &Service{
+		Now:       func() time.Time { return testNow },
+		threads:   newThreadStore(),
+		approvals: newApprovalQueue(),
+		reloaded:  make(chan struct{}, 1),
+	}
+	rt, err := newRuntimeConfig(config)
+	if err != nil {
+		t.Fatalf("newRuntimeConfig 返回错误: %v", err)
+	}
+	s.current.Store(rt)
+	return s
+}
+
+// 替换服务使用的通知渠道
+func setTestNotifiers(s *Service, notifiers ...Notifier) {
+	rt := *s.snapshot()
+	rt.notifiers = notifiers
+	s.current.Store(&rt)
+}
+
+// recordingNotifier 记录收到的通知，用于断言发送结果
+type recordingNotifier struct {
+	mutex sync.Mutex
+	sent  []Notification
+}
+
+func (r *recordingNotifier) Name() string {
+	return "recording"
+}
+
+func (r *recordingNotifier) Send(ctx context.Context, n Notification) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func (r *recordingNotifier) notifications() []Notification {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Notification(nil), r.sent...)
+}
+
+// fakeStatusPage 模拟状态页的 /api/v2/incidents.json 接口，设置了 etag 时支持 If-None-Match 条件请求
+type fakeStatusPage struct {
+	*httptest.Server
+
+	mutex           sync.Mutex
+	body            []byte
+	etag            string
+	requests        int
+	notModified     int
+	lastIfNoneMatch string
+}
+
+func newFakeStatusPage(t *testing.T) *fakeStatusPage {
+	t.Helper()
+	page := &fakeStatusPage{}
+	page.Server = httptest.NewServer(http.HandlerFunc(page.serve))
+	t.Cleanup(page.Close)
+	return page
+}
+
+// 设置接口返回的事件，etag 为空时不返回 ETag
+func (p *fakeStatusPage) setIncidents(t *testing.T, etag string, incidents ...Incident) {
+	t.Helper()
+	if incidents == nil {
+		incidents = []Incident{}
+	}
+	body, err := json.Marshal(Response{Incidents: incidents})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.setBody(etag, body)
+}
+
+// 直接设置接口返回的原始内容
+func (p *fakeStatusPage) setBody(etag string, body []byte) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.body, p.etag = body, etag
+}
+
+// 已收到的请求数、返回 304 的次数和最近一次请求的 If-None-Match
+func (p *fakeStatusPage) stats() (requests, notModified int, ifNoneMatch string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.requests, p.notModified, p.lastIfNoneMatch
+}
+
+func (p *fakeStatusPage) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v2/incidents.json" {
+		http.NotFound(w, r)
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.requests++
+	p.lastIfNoneMatch = r.Header.Get("If-None-Match")
+	if p.etag != "" {
+		w.Header().Set("ETag", p.etag)
+		if p.lastIfNoneMatch == p.etag {
+			p.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(p.body)
+}
+
+// fakeDingtalk 模拟钉钉机器人的 /robot/send 接口，按 baseTestConfig 中的 access_token 和加签密钥
+// 校验请求后记录收到的消息。调用 hold 后请求在 release 之前不返回，用于模拟发送过程中的其他操作
+type fakeDingtalk struct {
+	*httptest.Server
+	t *testing.T
+
+	mutex    sync.Mutex
+	messages []DingtalkMessage
+	held     chan struct{}
+	entered  chan struct{}
+}
+
+func newFakeDingtalk(t *testing.T) *fakeDingtalk {
+	t.Helper()
+	d := &fakeDingtalk{t: t, entered: make(chan struct{}, 16)}
+	d.Server = httptest.NewServer(http.HandlerFunc(d.serve))
+	t.Cleanup(d.Close)
+	return d
+}
+
+// 之后的请求阻塞到 release 调用为止
+func (d *fakeDingtalk) hold() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.held = make(chan struct{})
+}
+
+func (d *fakeDingtalk) release() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.held != nil {
+		close(d.held)
+		d.held = nil
+	}
+}
+
+func (d *fakeDingtalk) received() []DingtalkMessage {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]DingtalkMessage(nil), d.messages...)
+}
+
+func (d *fakeDingtalk) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/robot/send" {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	if token := query.Get("access_token"); token != "abc123" {
+		d.t.Errorf("钉钉请求 access_token = %q, 期望 abc123", token)
+		w.Write([]byte(`{"errcode":300001,"errmsg":"token is not exist"}`))
+		return
+	}
+	timestamp := query.Get("timestamp")
+	if want := generateDingtalkSign(timestamp, "SECxyz"); timestamp == "" || query.Get("sign") != want {
+		d.t.Errorf("钉钉请求签名不匹配: timestamp=%q, sign=%q, 期望 sign=%q", timestamp, query.Get("sign"), want)
+		w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+		return
+	}
+	var message DingtalkMessage
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mutex.Lock()
+	d.messages = append(d.messages, message)
+	held := d.held
+	d.mutex.Unlock()
+	d.entered <- struct{}{}
+	if held != nil {
+		<-held
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+}
+
+// 按服务当前的配置创建真实的通知渠道
+func buildTestNotifiers(t *testing.T, s *Service) {
+	t.Helper()
+	rt := *s.snapshot()
+	notifiers, err := buildNotifiers(s, &rt)
+	if err != nil {
+		t.Fatalf("初始化通知渠道失败: %v", err)
+	}
+	rt.notifiers = notifiers
+	s.current.Store(&rt)
+}
+
+// 测试用的事件，创建时间为 testNow 之前 age
+func testIncident(id, status, impact string, age time.Duration) Incident {
+	createdAt := testNow.Add(-age)
+	return Incident{
+		ID:        id,
+		Name:      "Incident " + id,
+		Status:    status,
+		Impact:    impact,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		IncidentUpdates: []Update{
+			{ID: id + "-u1", Status: status, Body: "We are investigating.", CreatedAt: createdAt, UpdatedAt: createdAt},
+		},
+	}
+}
+
+func TestStartupNotificationListsOldActiveIncidents(t *testing.T) {
+	s := newTestService(t, "")
+	incidents := []Incident{
+		{ID: "old-active", Name: "Long running outage", Status: "identified", Impact: "major",
+			CreatedAt: testNow.AddDate(0, 0, -10), UpdatedAt: testNow.Add(-time.Hour)},
+		{ID: "old-resolved", Name: "Old resolved incident", Status: "resolved", Impact: "minor",
+			CreatedAt: testNow.AddDate(0, 0, -10), UpdatedAt: testNow.AddDate(0, 0, -9)},
+	}
+
+	notifications, _ := s.detectChanges(incidents)
+	if len(notifications) != 1 || notifications[0].Kind != notifyKindStartup {
+		t.Fatalf("期望一条启动通知，实际: %+v", notifications)
+	}
+	content := notifications[0].Content
+	if !strings.Contains(content, "Long running outage") {
+		t.Errorf("启动通知未列出早于回溯窗口的未解决事件:\n%s", content)
+	}
+	if strings.Contains(content, "Old resolved incident") {
+		t.Errorf("启动通知不应列出早于回溯窗口的已解决事件:\n%s", content)
+	}
+	if len(s.lastIncidents) != 2 {
+		t.Errorf("缓存的事件数量 = %d, 期望 2", len(s.lastIncidents))
+	}
+}
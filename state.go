@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// persistedState 持久化到状态文件中的服务状态
+type persistedState struct {
+	SavedAt       time.Time           `json:"saved_at"`
+	StatusVersion string              `json:"status_version,omitempty"`
+	LastIncidents map[string]Incident `json:"last_incidents"`
+	// 已发送长时间未解决提醒的事件，避免 -once 模式下每次运行都重复提醒
+	LongIncidentAlerted map[string]bool `json:"long_incident_alerted,omitempty"`
+	// 各事件首次通知和最近通知的记录，用于 THREAD_UPDATES 和每日报告去重
+	Threads map[string]incidentThread `json:"threads,omitempty"`
+	// 已发送可用率告警的组件，恢复前不重复告警
+	UptimeBreached map[string]bool `json:"uptime_breached,omitempty"`
+	// 最近一次发送每日报告的时间，重启后同一发送时间不重复发送
+	LastReportTime time.Time `json:"last_report_time,omitempty"`
+	// STATUS_BOARD 各通知渠道的状态面板消息 ID、最近一次发送的事件列表和时间，重启后继续编辑同一条消息，
+	// 内容未变化时不重复发送
+	BoardMessageIDs map[string]string    `json:"board_message_ids,omitempty"`
+	BoardBodies     map[string]string    `json:"board_bodies,omitempty"`
+	BoardPostedAt   map[string]time.Time `json:"board_posted_at,omitempty"`
+	// APPROVAL_MODE 下尚未批准的通知，重启后继续等待批准或过期处理
+	PendingApprovals []pendingApproval `json:"pending_approvals,omitempty"`
+}
+
+// 从状态文件恢复事件缓存，文件不存在时视为首次运行
+func (s *Service) loadState() error {
+	data, err := ioutil.ReadFile(s.config().StateFile)
+	if os.IsNotExist(err) {
+		logInfof("状态文件不存在，将作为首次运行处理: %s", s.config().StateFile)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取状态文件失败: %v", err)
+	}
+
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		// 损坏的状态文件备份后按首次运行处理，避免服务无法启动
+		backup := s.config().StateFile + ".corrupt"
+		if renameErr := os.Rename(s.config().StateFile, backup); renameErr != nil {
+			return fmt.Errorf("解析状态文件失败: %v，且无法备份: %v", err, renameErr)
+		}
+		logEvent("error", "state", logFields{"error": err.Error(), "backup": backup},
+			"解析状态文件失败，已备份到 %s，将作为首次运行处理: %v", backup, err)
+		return nil
+	}
+	if state.LastIncidents == nil {
+		state.LastIncidents = make(map[string]Incident)
+	}
+
+	s.mutex.Lock()
+	s.lastIncidents = state.LastIncidents
+	s.statusVersion = state.StatusVersion
+	s.longIncidentAlerted = state.LongIncidentAlerted
+	s.uptimeBreached = state.UptimeBreached
+	s.lastReportTime = state.LastReportTime
+	s.boardMessageIDs = state.BoardMessageIDs
+	s.boardBodies = state.BoardBodies
+	s.boardPostedAt = state.BoardPostedAt
+	s.mutex.Unlock()
+	s.threads.Restore(state.Threads)
+	s.approvals.Restore(state.PendingApprovals)
+
+	logInfof("已从状态文件恢复 %d 个事件，保存时间: %s",
+		len(state.LastIncidents), state.SavedAt.Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// 将事件缓存写入状态文件，超过保留期限的事件不写入
+func (s *Service) saveState() error {
+	now := s.Now()
+	retentionCutoff := now.AddDate(0, 0, -s.config().CacheRetentionDays)
+
+	s.mutex.RLock()
+	incidents := make(map[string]Incident, len(s.lastIncidents))
+	for id, incident := range s.lastIncidents {
+		if !s.cacheExpired(incident, retentionCutoff) {
+			incidents[id] = incident
+		}
+	}
+	state := persistedState{
+		SavedAt:             now,
+		StatusVersion:       s.statusVersion,
+		LastIncidents:       incidents,
+		LongIncidentAlerted: s.longIncidentAlerted,
+		UptimeBreached:      s.uptimeBreached,
+		LastReportTime:      s.lastReportTime,
+		BoardMessageIDs:     s.boardMessageIDs,
+		BoardBodies:         s.boardBodies,
+		BoardPostedAt:       s.boardPostedAt,
+		Threads: s.threads.Snapshot(func(id string) bool {
+			_, ok := incidents[id]
+			return ok
+		}),
+		PendingApprovals: s.approvals.Snapshot(),
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	s.mutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %v", err)
+	}
+
+	if err := writeFileAtomic(s.config().StateFile, data, 0600); err != nil {
+		return fmt.Errorf("写入状态文件失败: %v", err)
+	}
+	logDebugf("状态已保存到 %s，共 %d 个事件", s.config().StateFile, len(state.LastIncidents))
+	return nil
+}
+
+// 先写入同目录下的临时文件再重命名，写入过程中崩溃不会留下不完整的文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
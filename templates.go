@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 模板中可用的命名模板块
+const (
+	templateNew      = "new"
+	templateUpdate   = "update"
+	templateResolved = "resolved"
+	templateDaily    = "daily"
+)
+
+// incidentTemplateData 渲染事件模板时传入的数据
+type incidentTemplateData struct {
+	Incident
+	Updates []Update // 本次需要展示的更新记录
+	URL     string   // 事件链接
+}
+
+// 模板中可用的辅助函数，formatTime 使用默认格式，加载通知模板时替换为 TIME_FORMAT
+var templateFuncs = template.FuncMap{
+	"formatTime": timeFormatter(defaultTimeFormat),
+	"duration": func(from, to time.Time) string {
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return ""
+		}
+		return to.Sub(from).Round(time.Minute).String()
+	},
+	"sanitize": sanitizeUpdateBody,
+	"upper":    strings.ToUpper,
+	"badge":    incidentBadge,
+	// 加载通知模板时替换为 IMPACT_LABELS 中的名称
+	"impactLabel": func(impact string) string { return impact },
+}
+
+// 加载并校验通知模板，语法或字段错误会在启动时直接报错。模板中的 formatTime 按 layout 格式化时间，
+// badge 和 impactLabel 使用 labels 中的影响程度名称
+func loadTemplates(path, layout string, labels map[string]string) (*template.Template, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取模板文件失败: %v", err)
+	}
+
+	label := func(impact string) string {
+		if label, ok := labels[impact]; ok {
+			return label
+		}
+		return impact
+	}
+	overrides := template.FuncMap{
+		"formatTime":  timeFormatter(layout),
+		"impactLabel": label,
+		"badge": func(incident Incident) string {
+			if l, ok := labels[incident.Impact]; ok {
+				return impactBadge(incident.Status, incident.Impact, l)
+			}
+			return incidentBadge(incident)
+		},
+	}
+	tmpl, err := template.New("notification").Funcs(templateFuncs).Funcs(overrides).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("解析模板文件失败: %v", err)
+	}
+
+	// 使用示例数据试渲染每个命名模板，提前发现引用了不存在字段等错误
+	now := time.Now()
+	sample := incidentTemplateData{
+		Incident: Incident{
+			ID:        "sample",
+			Name:      "示例事件",
+			Status:    "investigating",
+			Impact:    "minor",
+			CreatedAt: now,
+			UpdatedAt: now,
+			IncidentUpdates: []Update{
+				{ID: "sample-update", Status: "investigating", Body: "示例更新", CreatedAt: now, UpdatedAt: now},
+			},
+		},
+		URL: "https://www.cloudflarestatus.com/incidents/sample",
+	}
+	sample.Updates = sample.IncidentUpdates
+
+	for _, name := range []string{templateNew, templateUpdate, templateResolved, templateDaily} {
+		if tmpl.Lookup(name) == nil {
+			continue
+		}
+		if err := tmpl.ExecuteTemplate(ioutil.Discard, name, sample); err != nil {
+			return nil, fmt.Errorf("模板 %s 渲染失败: %v", name, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// 渲染事件详情，配置了对应命名模板时使用模板，否则使用内置格式
+func (s *Service) renderIncident(name string, incident Incident, updates []Update) string {
+	if out, ok := s.renderTemplate(name, incident, updates); ok {
+		return out
+	}
+	return s.formatIncidentDetails(incident, updates)
+}
+
+// 使用命名模板渲染事件，未配置该模板或渲染失败时返回 false
+func (s *Service) renderTemplate(name string, incident Incident, updates []Update) (string, bool) {
+	tmpl := s.snapshot().templates
+	if tmpl == nil || tmpl.Lookup(name) == nil {
+		return "", false
+	}
+	var out strings.Builder
+	data := incidentTemplateData{
+		Incident: incident,
+		Updates:  updates,
+		URL:      s.incidentLink(incident),
+	}
+	if err := tmpl.ExecuteTemplate(&out, name, data); err != nil {
+		logEvent("error", "template", logFields{"template": name, "incident_id": incident.ID, "error": err.Error()},
+			"模板 %s 渲染失败，使用内置格式: %v", name, err)
+		return "", false
+	}
+	return out.String(), true
+}
+
+// 变更通知标题模板的默认值，与未配置模板时的标题一致；逐条发送时 .Name 不为空
+const defaultTitleTemplate = "Cloudflare 状态更新{{if .Name}}: {{.Name}} [{{.Impact}}]{{end}}"
+
+// 通知页脚模板的默认值，链接到通知涉及的状态页
+const defaultFooterTemplate = "详细状态请访问: {{range $i, $url := .StatusPageURLs}}{{if $i}} {{end}}{{$url}}/{{end}}"
+
+// 未预先解析模板或模板执行失败时使用的默认模板
+var (
+	fallbackTitleTemplate  = template.Must(template.New("title").Parse(defaultTitleTemplate))
+	fallbackFooterTemplate = template.Must(template.New("footer").Parse(defaultFooterTemplate))
+)
+
+// footerTemplateData 渲染通知页脚模板时传入的数据
+type footerTemplateData struct {
+	StatusPageURL  string   // 通知涉及的第一个状态页地址
+	StatusPageURLs []string // 通知涉及的全部状态页地址
+}
+
+// 解析通知页脚模板并用示例数据试渲染，用于启动时校验
+func parseFooterTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("NOTIFICATION_FOOTER").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	sample := footerTemplateData{StatusPageURL: "https://www.cloudflarestatus.com", StatusPageURLs: []string{"https://www.cloudflarestatus.com"}}
+	if err := tmpl.Execute(ioutil.Discard, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// 生成通知页脚。pages 为通知涉及的状态页，为空时使用全部监控的状态页
+func (s *Service) notificationFooter(pages []string) string {
+	rt := s.snapshot()
+	if len(pages) == 0 {
+		pages = rt.config.StatusPages
+	}
+	data := footerTemplateData{StatusPageURLs: pages}
+	if len(pages) > 0 {
+		data.StatusPageURL = pages[0]
+	}
+
+	tmpl := rt.footerTemplate
+	if tmpl == nil {
+		tmpl = fallbackFooterTemplate
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		logWarnf("NOTIFICATION_FOOTER 渲染失败，使用默认页脚: %v", err)
+		out.Reset()
+		fallbackFooterTemplate.Execute(&out, data)
+	}
+	return out.String()
+}
+
+// 变化涉及的状态页地址，按首次出现的顺序去重
+func changePages(changes []incidentChange) []string {
+	var pages []string
+	seen := make(map[string]bool)
+	for _, change := range changes {
+		if page := change.Event.Incident.Page; page != "" && !seen[page] {
+			seen[page] = true
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// 影响程度对应的图标，供标题模板使用
+var impactEmojis = map[string]string{
+	"critical": "🔴",
+	"major":    "🟠",
+	"minor":    "🟡",
+	"none":     "⚪",
+}
+
+// titleTemplateData 渲染通知标题模板时传入的数据
+type titleTemplateData struct {
+	Count    int    // 本条通知包含的变化数量
+	Impact   string // 变化中最高的影响程度，配置 IMPACT_LABELS 时为对应名称
+	Emoji    string // 最高影响程度对应的图标
+	Page     string // 变化所属状态页的主机名，多个时以逗号分隔
+	PageName string // 变化所属状态页的显示名称，多个时以逗号分隔
+	Name     string // 只包含一个变化时为事件名称，否则为空
+}
+
+// 解析通知标题模板并用示例数据试渲染，用于启动时校验
+func parseTitleTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	sample := titleTemplateData{Count: 1, Impact: "minor", Emoji: impactEmojis["minor"], Page: "www.cloudflarestatus.com", PageName: "Cloudflare"}
+	if err := tmpl.Execute(ioutil.Discard, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// 生成变更通知标题：全部是新事件时使用 NEW_TITLE_TEMPLATE，否则使用 UPDATE_TITLE_TEMPLATE。
+// single 为 true 时表示逐条发送，标题中可以使用事件名称
+func (s *Service) changeTitle(changes []incidentChange, single bool) string {
+	data := titleTemplateData{Count: len(changes), Impact: "none"}
+	allNew := true
+	var pages, pageNames []string
+	seenPages := make(map[string]bool)
+	for _, change := range changes {
+		incident := change.Event.Incident
+		if impactRank[incident.Impact] > impactRank[data.Impact] {
+			data.Impact = incident.Impact
+		}
+		allNew = allNew && change.Event.ChangeType == changeTypeNew
+		page := incident.Page
+		if parsed, err := url.Parse(page); err == nil && parsed.Host != "" {
+			page = parsed.Host
+		}
+		if page != "" && !seenPages[page] {
+			seenPages[page] = true
+			pages = append(pages, page)
+			pageNames = append(pageNames, s.pageName(incident.Page))
+		}
+	}
+	data.Emoji = impactEmojis[data.Impact]
+	data.Impact = s.impactLabel(data.Impact)
+	data.Page = strings.Join(pages, ",")
+	data.PageName = strings.Join(pageNames, ",")
+	if single && len(changes) == 1 {
+		data.Name = changes[0].Event.Incident.Name
+	}
+
+	rt := s.snapshot()
+	name, tmpl := "UPDATE_TITLE_TEMPLATE", rt.updateTitleTemplate
+	if allNew {
+		name, tmpl = "NEW_TITLE_TEMPLATE", rt.newTitleTemplate
+	}
+	if tmpl == nil {
+		tmpl = fallbackTitleTemplate
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		logWarnf("%s 渲染失败，使用默认标题: %v", name, err)
+		out.Reset()
+		fallbackTitleTemplate.Execute(&out, data)
+	}
+	return strings.TrimSpace(out.String())
+}
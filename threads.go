@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// incidentThread 事件首次通知的记录，用于将后续更新关联到首次通知
+type incidentThread struct {
+	AnnouncedAt time.Time `json:"announced_at"`
+	// 最近一次包含该事件的变更通知发送成功的时间，用于每日报告去重
+	LastNotifiedAt time.Time `json:"last_notified_at,omitempty"`
+	// 支持回复的通知渠道名称 -> 首次通知时该渠道返回的消息 ID
+	MessageIDs map[string]string `json:"message_ids,omitempty"`
+}
+
+// threadStore 按事件 ID 记录首次通知的消息，通知渠道在发送 goroutine 中读写，有独立的锁
+type threadStore struct {
+	mutex   sync.Mutex
+	threads map[string]incidentThread
+}
+
+func newThreadStore() *threadStore {
+	return &threadStore{threads: make(map[string]incidentThread)}
+}
+
+// 记录事件已发送过新事件通知，重复调用保留最早的时间
+func (t *threadStore) MarkAnnounced(incidentID string, at time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	thread, ok := t.threads[incidentID]
+	if ok && !thread.AnnouncedAt.IsZero() {
+		return
+	}
+	thread.AnnouncedAt = at
+	t.threads[incidentID] = thread
+}
+
+// 事件首次通知的时间
+func (t *threadStore) Announced(incidentID string) (time.Time, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	thread, ok := t.threads[incidentID]
+	return thread.AnnouncedAt, ok && !thread.AnnouncedAt.IsZero()
+}
+
+// 记录事件最近一次单独通知的时间
+func (t *threadStore) MarkNotified(incidentID string, at time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	thread := t.threads[incidentID]
+	thread.LastNotifiedAt = at
+	t.threads[incidentID] = thread
+}
+
+// 事件最近一次单独通知的时间
+func (t *threadStore) LastNotified(incidentID string) (time.Time, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	thread, ok := t.threads[incidentID]
+	return thread.LastNotifiedAt, ok && !thread.LastNotifiedAt.IsZero()
+}
+
+// 查找事件在某个通知渠道中首次通知的消息 ID
+func (t *threadStore) MessageID(incidentID, notifier string) (string, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	id, ok := t.threads[incidentID].MessageIDs[notifier]
+	return id, ok
+}
+
+// 记录事件在某个通知渠道中首次通知的消息 ID，已有记录时不覆盖
+func (t *threadStore) RecordMessageID(incidentID, notifier, messageID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	thread := t.threads[incidentID]
+	if _, ok := thread.MessageIDs[notifier]; ok {
+		return
+	}
+	if thread.MessageIDs == nil {
+		thread.MessageIDs = make(map[string]string)
+	}
+	thread.MessageIDs[notifier] = messageID
+	t.threads[incidentID] = thread
+}
+
+// 返回 keep 为 true 的事件的记录副本，用于写入状态文件
+func (t *threadStore) Snapshot(keep func(incidentID string) bool) map[string]incidentThread {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	snapshot := make(map[string]incidentThread)
+	for id, thread := range t.threads {
+		if keep(id) {
+			snapshot[id] = thread
+		}
+	}
+	return snapshot
+}
+
+// 从状态文件恢复记录
+func (t *threadStore) Restore(threads map[string]incidentThread) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for id, thread := range threads {
+		t.threads[id] = thread
+	}
+}
+
+// 开启 THREAD_UPDATES 后，不支持回复的渠道在后续更新中注明首次通知的时间，便于对应到同一事件
+func (s *Service) threadReference(incident Incident) string {
+	if !s.config().ThreadUpdates {
+		return ""
+	}
+	announcedAt, ok := s.threads.Announced(incident.ID)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("> 🧵 事件「%s」的后续更新，首次通知于 %s\n\n", incident.Name, s.formatTime(announcedAt))
+}
+
+// 通知发送成功后记录其中各事件的通知时间，开启 THREAD_UPDATES 时同时记录新事件的首次通知，调用方无需持有锁
+func (s *Service) recordNotified(n Notification) {
+	now := s.Now()
+	for _, event := range n.Events {
+		s.threads.MarkNotified(event.Incident.ID, now)
+		if s.config().ThreadUpdates && event.ChangeType == changeTypeNew {
+			s.threads.MarkAnnounced(event.Incident.ID, now)
+		}
+	}
+}
+
+// 开启 REPORT_DEDUP_WINDOW_MINUTES 后，判断事件是否在窗口内单独通知过，同时返回通知时间
+func (s *Service) recentlyNotified(incident Incident) (time.Time, bool) {
+	if s.config().ReportDedupWindowMinutes <= 0 {
+		return time.Time{}, false
+	}
+	notifiedAt, ok := s.threads.LastNotified(incident.ID)
+	if !ok || s.Now().Sub(notifiedAt) > time.Duration(s.config().ReportDedupWindowMinutes)*time.Minute {
+		return time.Time{}, false
+	}
+	return notifiedAt, true
+}
+
+// 从 Webhook 接收方的响应中读取首次通知的消息 ID，支持 message_id 或 thread_id 字段
+func parseWebhookMessageID(body []byte) string {
+	var response struct {
+		MessageID string `json:"message_id"`
+		ThreadID  string `json:"thread_id"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+	if response.ThreadID != "" {
+		return response.ThreadID
+	}
+	return response.MessageID
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 可调整的测试时钟
+type testClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *testClock) set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = t
+}
+
+// 连接模拟状态页和模拟钉钉的服务，每日报告在 UTC 12 点发送
+func newTickTestService(t *testing.T) (*Service, *fakeStatusPage, *fakeDingtalk, *testClock) {
+	t.Helper()
+	page := newFakeStatusPage(t)
+	dingtalk := newFakeDingtalk(t)
+	s := newTestService(t, "STATUS_PAGE_URL="+page.URL+"\nDINGTALK_BASE_URL="+dingtalk.URL+
+		"\nDAILY_REPORT_UTC_HOUR=12\nSEND_STARTUP_NOTIFICATION=false\n")
+	clock := &testClock{now: testNow}
+	s.Now = clock.Now
+	buildTestNotifiers(t, s)
+	return s, page, dingtalk, clock
+}
+
+func dailyReports(messages []DingtalkMessage) int {
+	count := 0
+	for _, m := range messages {
+		if m.Markdown.Title == "Cloudflare 每日状态报告" {
+			count++
+		}
+	}
+	return count
+}
+
+// 新事件通知的完整内容，{page} 替换为模拟状态页的地址
+const wantNewIncidentText = `# Cloudflare 状态更新
+
+时间: 2024-03-01 11:05:00
+
+
+## 新事件
+### 事件: Incident inc2
+- ID: inc2
+- 状态: investigating
+- 影响程度: major
+- 创建时间: 2024-03-01 11:04:00
+- 更新时间: 2024-03-01 11:04:00
+
+更新历史:
+- 2024-03-01 11:04:00 [investigating]: We are investigating.
+
+事件链接: {page}/incidents/inc2
+
+
+
+---
+详细状态请访问: {page}/`
+
+// 事件解决通知的完整内容
+const wantResolvedText = `# Cloudflare 状态更新
+
+时间: 2024-03-01 12:15:00
+
+
+## 事件更新
+### 事件: Incident inc2
+- ID: inc2
+- 状态: resolved
+- 影响程度: major
+- 创建时间: 2024-03-01 11:04:00
+- 更新时间: 2024-03-01 12:15:00
+- 解决时间: 2024-03-01 12:15:00
+
+最新更新（共 2 条）:
+- 2024-03-01 12:15:00 [resolved]: This incident has been resolved.
+
+事件链接: {page}/incidents/inc2
+
+
+
+---
+详细状态请访问: {page}/`
+
+func TestRunTickEndToEnd(t *testing.T) {
+	s, page, dingtalk, clock := newTickTestService(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	// testIncident 按距 testNow 的时长生成创建时间
+	inc1 := testIncident("inc1", "investigating", "minor", testNow.Sub(day.Add(10*time.Hour)))
+	inc2 := testIncident("inc2", "investigating", "major", testNow.Sub(day.Add(11*time.Hour+4*time.Minute)))
+	golden := func(text string) string { return strings.ReplaceAll(text, "{page}", page.URL) }
+
+	// 首次检查只建立缓存
+	clock.set(day.Add(11 * time.Hour))
+	page.setIncidents(t, "", inc1)
+	if err := s.runTick(ctx, true); err != nil {
+		t.Fatalf("首次检查失败: %v", err)
+	}
+	if got := dingtalk.received(); len(got) != 0 {
+		t.Fatalf("关闭启动通知时首次检查不应发送消息，实际: %+v", got)
+	}
+
+	// 新事件触发变化通知，未到发送时间不发送每日报告
+	clock.set(day.Add(11*time.Hour + 5*time.Minute))
+	page.setIncidents(t, "", inc1, inc2)
+	if err := s.runTick(ctx, true); err != nil {
+		t.Fatalf("第二次检查失败: %v", err)
+	}
+	changes := dingtalk.received()
+	if len(changes) != 1 {
+		t.Fatalf("新事件发送了 %d 条钉钉通知，期望 1 条: %+v", len(changes), changes)
+	}
+	if got := changes[0].Markdown.Title; got != "Cloudflare 状态更新" {
+		t.Errorf("新事件通知标题 = %q", got)
+	}
+	if got, want := changes[0].Markdown.Text, golden(wantNewIncidentText); got != want {
+		t.Errorf("新事件通知内容:\n%s\n期望:\n%s", got, want)
+	}
+
+	// 到达发送时间，事件没有变化，只发送每日报告
+	clock.set(day.Add(12*time.Hour + 5*time.Minute))
+	if err := s.runTick(ctx, true); err != nil {
+		t.Fatalf("第三次检查失败: %v", err)
+	}
+	report := dingtalk.received()[len(changes):]
+	if len(report) != 1 || dailyReports(report) != 1 {
+		t.Fatalf("到达发送时间后钉钉收到的消息 = %+v, 期望一条每日报告", report)
+	}
+	if !strings.Contains(report[0].Markdown.Text, "Incident inc2") {
+		t.Errorf("每日报告未包含缓存的事件:\n%s", report[0].Markdown.Text)
+	}
+
+	// 同一发送时间不重复发送
+	clock.set(day.Add(12*time.Hour + 10*time.Minute))
+	if err := s.runTick(ctx, true); err != nil {
+		t.Fatalf("第四次检查失败: %v", err)
+	}
+	if n := dailyReports(dingtalk.received()); n != 1 {
+		t.Errorf("同一发送时间发送了 %d 次每日报告，期望 1 次", n)
+	}
+
+	// inc2 解决后发送解决通知
+	sent := len(dingtalk.received())
+	resolvedAt := day.Add(12*time.Hour + 15*time.Minute)
+	clock.set(resolvedAt)
+	resolved := inc2
+	resolved.Status = "resolved"
+	resolved.UpdatedAt = resolvedAt
+	resolved.ResolvedAt = resolvedAt
+	resolved.IncidentUpdates = append([]Update{{ID: "inc2-u2", Status: "resolved", Body: "This incident has been resolved.",
+		CreatedAt: resolvedAt, UpdatedAt: resolvedAt}}, inc2.IncidentUpdates...)
+	page.setIncidents(t, "", inc1, resolved)
+	if err := s.runTick(ctx, true); err != nil {
+		t.Fatalf("第五次检查失败: %v", err)
+	}
+	resolution := dingtalk.received()[sent:]
+	if len(resolution) != 1 {
+		t.Fatalf("事件解决后发送了 %d 条钉钉通知，期望 1 条: %+v", len(resolution), resolution)
+	}
+	if got, want := resolution[0].Markdown.Text, golden(wantResolvedText); got != want {
+		t.Errorf("解决通知内容:\n%s\n期望:\n%s", got, want)
+	}
+}
+
+// 获取数据失败时主循环仍发送基于缓存的每日报告，-once 模式不发送
+func TestRunTickFetchError(t *testing.T) {
+	tests := []struct {
+		name               string
+		reportOnFetchError bool
+		wantReports        int
+	}{
+		{name: "主循环", reportOnFetchError: true, wantReports: 1},
+		{name: "-once 模式", reportOnFetchError: false, wantReports: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, page, dingtalk, clock := newTickTestService(t)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			clock.set(time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC))
+			page.setIncidents(t, "", testIncident("inc1", "investigating", "minor", time.Hour))
+			if err := s.runTick(ctx, tt.reportOnFetchError); err != nil {
+				t.Fatalf("首次检查失败: %v", err)
+			}
+
+			clock.set(time.Date(2024, 3, 1, 12, 5, 0, 0, time.UTC))
+			page.setBody("", []byte("not json"))
+			if err := s.runTick(ctx, tt.reportOnFetchError); err == nil {
+				t.Fatal("获取数据失败时 runTick 应返回错误")
+			}
+			if n := dailyReports(dingtalk.received()); n != tt.wantReports {
+				t.Errorf("发送了 %d 次每日报告，期望 %d 次", n, tt.wantReports)
+			}
+		})
+	}
+}
+
+func TestRunOnceErrors(t *testing.T) {
+	s, _, _, _ := newTickTestService(t)
+	if err := runOnce(s); err == nil || !strings.Contains(err.Error(), "STATE_FILE") {
+		t.Errorf("未配置 STATE_FILE 时 runOnce 返回 %v, 期望 STATE_FILE 相关的错误", err)
+	}
+
+	s, page, dingtalk, _ := newTickTestService(t)
+	rt := *s.snapshot()
+	rt.config.StateFile = filepath.Join(t.TempDir(), "state.json")
+	s.current.Store(&rt)
+	page.setBody("", []byte("not json"))
+	if err := runOnce(s); err == nil {
+		t.Error("获取数据失败时 runOnce 应返回错误")
+	}
+	if got := dingtalk.received(); len(got) != 0 {
+		t.Errorf("获取数据失败时 -once 模式不应发送消息，实际: %+v", got)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 事件时间线页面模板，html/template 会对事件内容做转义
+var timelineTemplate = template.Must(template.New("timeline").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+	"sanitize": sanitizeUpdateBody,
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} - 事件时间线</title>
+<style>
+body { font-family: sans-serif; max-width: 860px; margin: 2em auto; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 6px 10px; text-align: left; vertical-align: top; }
+th { background: #f5f5f5; }
+td.body { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>
+事件 ID: {{.ID}}<br>
+影响程度: {{.Impact}}<br>
+创建时间: {{formatTime .CreatedAt}}<br>
+解决时间: {{formatTime .ResolvedAt}}<br>
+持续时长: {{.Duration}}
+{{- if .Shortlink}}<br>
+状态页: <a href="{{.Shortlink}}">{{.Shortlink}}</a>{{end}}
+</p>
+<table>
+<tr><th>时间</th><th>状态</th><th>内容</th></tr>
+{{- range .Updates}}
+<tr><td>{{formatTime .CreatedAt}}</td><td>{{.Status}}</td><td class="body">{{sanitize .Body}}</td></tr>
+{{- end}}
+</table>
+<p>生成时间: {{formatTime .GeneratedAt}}</p>
+</body>
+</html>
+`))
+
+// timelineData 渲染时间线页面时传入的数据
+type timelineData struct {
+	Incident
+	ResolvedAt  time.Time
+	Duration    string
+	Updates     []Update // 按时间正序排列的全部更新
+	GeneratedAt time.Time
+}
+
+// 将已解决事件的完整时间线写入 TIMELINE_DIR 下的 HTML 文件，返回通知中使用的链接。
+// 配置了 TIMELINE_BASE_URL 时返回可访问的 URL，否则返回文件路径
+func (s *Service) writeTimeline(incident Incident) (string, error) {
+	updates := append([]Update(nil), incident.IncidentUpdates...)
+	sort.SliceStable(updates, func(i, j int) bool {
+		return updates[i].CreatedAt.Before(updates[j].CreatedAt)
+	})
+
+	duration := "-"
+	if elapsed, resolved := incident.resolutionDuration(); resolved {
+		duration = elapsed.Round(time.Minute).String()
+	}
+
+	var buf bytes.Buffer
+	err := timelineTemplate.Execute(&buf, timelineData{
+		Incident:    incident,
+		ResolvedAt:  incident.resolvedTime(),
+		Duration:    duration,
+		Updates:     updates,
+		GeneratedAt: s.Now(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("渲染事件时间线失败: %v", err)
+	}
+
+	// 事件 ID 来自外部数据，只取最后一段避免写到目录之外
+	name := filepath.Base(incident.ID) + ".html"
+	path := filepath.Join(s.config().TimelineDir, name)
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("写入事件时间线失败: %v", err)
+	}
+
+	if s.config().TimelineBaseURL == "" {
+		return path, nil
+	}
+	return strings.TrimRight(s.config().TimelineBaseURL, "/") + "/" + url.PathEscape(name), nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 可用率相关的计算参数
+const (
+	uptimeWindowDays   = 90        // 滚动计算可用率的天数
+	uptimePollInterval = time.Hour // 可用率按天更新，不需要每轮检查都获取
+	// Statuspage 计算可用率时部分中断按 30% 计入不可用时长，严重中断全部计入
+	uptimePartialOutageWeight = 0.3
+)
+
+// UptimeDay 组件一天的中断时长
+type UptimeDay struct {
+	Date    string `json:"date"` // 2006-01-02
+	Outages struct {
+		Partial int64 `json:"p"` // 部分中断秒数
+		Major   int64 `json:"m"` // 严重中断秒数
+	} `json:"outages"`
+}
+
+// UptimeResponse 结构体用于解析 /uptime/<组件ID>.json，按月分组列出每天的中断时长
+type UptimeResponse struct {
+	Months []struct {
+		Days []UptimeDay `json:"days"`
+	} `json:"months"`
+}
+
+// 计算截至 now 的滚动可用率百分比，只统计最近 uptimeWindowDays 天；没有任何数据时返回 false
+func (r UptimeResponse) rollingUptime(now time.Time) (float64, bool) {
+	cutoff := now.AddDate(0, 0, -uptimeWindowDays).Format("2006-01-02")
+	today := now.Format("2006-01-02")
+	days := 0
+	var downtime float64
+	for _, month := range r.Months {
+		for _, day := range month.Days {
+			if day.Date <= cutoff || day.Date > today {
+				continue
+			}
+			days++
+			downtime += float64(day.Outages.Major) + uptimePartialOutageWeight*float64(day.Outages.Partial)
+		}
+	}
+	if days == 0 {
+		return 0, false
+	}
+	uptime := 100 * (1 - downtime/float64(days*86400))
+	if uptime < 0 {
+		uptime = 0
+	}
+	return uptime, true
+}
+
+// 获取单个组件的可用率数据
+func (s *Service) fetchComponentUptime(ctx context.Context, component Component) (UptimeResponse, error) {
+	var uptime UptimeResponse
+	resp, err := s.getStatusPage(ctx, component.Page, "/uptime/"+url.PathEscape(component.ID)+".json", nil)
+	if err != nil {
+		return uptime, fmt.Errorf("获取可用率失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return uptime, fmt.Errorf("获取可用率返回异常状态码: %d", resp.StatusCode)
+	}
+
+	body, err := readLimited(resp.Body, s.config().MaxResponseBytes)
+	if err != nil {
+		return uptime, fmt.Errorf("读取可用率失败: %v", err)
+	}
+	if err := json.Unmarshal(body, &uptime); err != nil {
+		return uptime, fmt.Errorf("解析可用率失败: %v", err)
+	}
+	return uptime, nil
+}
+
+// uptimeBreach 一个可用率低于阈值的组件
+type uptimeBreach struct {
+	Component Component
+	Uptime    float64
+}
+
+// 检查状态页上展示可用率的组件，90 天滚动可用率低于 UPTIME_SLA_THRESHOLD 时发送一次告警，
+// 恢复到阈值以上后清除记录，再次低于阈值时重新告警。每小时最多检查一次
+func (s *Service) checkUptimeSLA(ctx context.Context) {
+	now := s.Now()
+	s.mutex.RLock()
+	due := s.lastUptimeCheck.IsZero() || now.Sub(s.lastUptimeCheck) >= uptimePollInterval
+	s.mutex.RUnlock()
+	if !due {
+		return
+	}
+
+	var components []Component
+	for _, page := range s.config().StatusPages {
+		pageComponents, err := s.fetchPageComponents(ctx, page)
+		if err != nil {
+			logErrorf("状态页 %s 组件列表获取失败，跳过可用率检查: %v", page, err)
+			return
+		}
+		for _, component := range pageComponents {
+			// 只有在状态页上展示可用率条的组件才有可用率数据
+			if !component.Showcase {
+				continue
+			}
+			if len(s.config().WatchComponents) > 0 && !s.componentWatched(component) {
+				continue
+			}
+			components = append(components, component)
+		}
+	}
+	logDebugf("检查 %d 个组件的可用率", len(components))
+
+	uptimes := make(map[string]float64)
+	for _, component := range components {
+		response, err := s.fetchComponentUptime(ctx, component)
+		if err != nil {
+			logWarnf("组件 %s 可用率获取失败: %v", component.Name, err)
+			continue
+		}
+		if uptime, ok := response.rollingUptime(now); ok {
+			uptimes[component.Page+"|"+component.ID] = uptime
+			logDebugf("组件 %s 近 %d 天可用率: %.3f%%", component.Name, uptimeWindowDays, uptime)
+		}
+	}
+
+	s.mutex.Lock()
+	s.lastUptimeCheck = now
+	if s.uptimeBreached == nil {
+		s.uptimeBreached = make(map[string]bool)
+	}
+	var breaches []uptimeBreach
+	for _, component := range components {
+		key := component.Page + "|" + component.ID
+		uptime, ok := uptimes[key]
+		if !ok {
+			continue
+		}
+		if uptime >= s.config().UptimeSLAThreshold {
+			if s.uptimeBreached[key] {
+				logInfof("组件 %s 可用率已恢复到 %.3f%%，不低于阈值 %.3f%%", component.Name, uptime, s.config().UptimeSLAThreshold)
+				delete(s.uptimeBreached, key)
+			}
+			continue
+		}
+		if s.uptimeBreached[key] {
+			continue
+		}
+		s.uptimeBreached[key] = true
+		breaches = append(breaches, uptimeBreach{Component: component, Uptime: uptime})
+	}
+	header := notificationHeader(s.statusVersion, s.formatTime(now))
+	s.mutex.Unlock()
+
+	if len(breaches) == 0 {
+		return
+	}
+	sort.Slice(breaches, func(i, j int) bool {
+		return breaches[i].Uptime < breaches[j].Uptime
+	})
+
+	var lines []string
+	for _, breach := range breaches {
+		logEvent("warn", "uptime", logFields{"component": breach.Component.Name, "uptime": breach.Uptime},
+			"组件可用率低于阈值 - %s: %.3f%%", breach.Component.Name, breach.Uptime)
+		lines = append(lines, fmt.Sprintf("- **%s**: %.3f%%\n", breach.Component.Name, breach.Uptime))
+	}
+	content := "# Cloudflare 组件可用率低于 SLA\n\n" + header +
+		fmt.Sprintf("以下组件近 %d 天的可用率低于 %.3f%%:\n\n", uptimeWindowDays, s.config().UptimeSLAThreshold) +
+		strings.Join(lines, "") + "\n---\n" +
+		s.notificationFooter(nil)
+	if err := s.notify(ctx, Notification{
+		Kind:    notifyKindUptime,
+		Title:   "Cloudflare 组件可用率低于 SLA",
+		Content: content,
+	}); err != nil {
+		logErrorf("发送可用率告警失败: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 状态页接口中事件和事件更新可能出现的状态，包括计划维护使用的状态
+var knownIncidentStatuses = map[string]bool{
+	"investigating": true,
+	"identified":    true,
+	"monitoring":    true,
+	"resolved":      true,
+	"postmortem":    true,
+	"scheduled":     true,
+	"in_progress":   true,
+	"verifying":     true,
+	"completed":     true,
+}
+
+// 解析状态页事件接口的响应并校验其中的事件。响应中没有 incidents 字段时返回错误，
+// 通常说明上游返回了错误页或接口格式已变化；不合法的事件被跳过，其余事件照常处理
+func decodeIncidents(page string, body []byte) ([]Incident, error) {
+	var response Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("JSON 解析失败: %v（响应开头: %s）", err, responseSnippet(body))
+	}
+	if response.Incidents == nil {
+		return nil, fmt.Errorf("响应中缺少 incidents 字段，可能不是 Statuspage 事件接口（响应开头: %s）", responseSnippet(body))
+	}
+	return validateIncidents(page, response.Incidents), nil
+}
+
+// 响应内容的开头部分，用于错误信息
+func responseSnippet(body []byte) string {
+	const limit = 120
+	snippet := []rune(strings.Join(strings.Fields(string(body)), " "))
+	if len(snippet) > limit {
+		return string(snippet[:limit]) + "…"
+	}
+	return string(snippet)
+}
+
+// 校验事件的基本约束：ID 不为空、状态属于已知状态、创建时间可以解析。
+// 不满足的事件记录详细原因后跳过；事件更新缺少 ID 或创建时间时只去掉该条更新。
+// 重复的 ID 不在这里处理，由 dedupeIncidents 按 DUPLICATE_INCIDENT_POLICY 选择保留的一条
+func validateIncidents(page string, incidents []Incident) []Incident {
+	valid := incidents[:0]
+	for i, incident := range incidents {
+		var problems []string
+		if strings.TrimSpace(incident.ID) == "" {
+			problems = append(problems, "id 为空")
+		}
+		if !knownIncidentStatuses[incident.Status] {
+			problems = append(problems, fmt.Sprintf("未知的状态 %q", incident.Status))
+		}
+		if incident.CreatedAt.IsZero() {
+			problems = append(problems, "created_at 缺失或无法解析")
+		}
+		if len(problems) > 0 {
+			logEvent("warn", "fetch", logFields{"page": page, "index": i, "incident_id": incident.ID, "problems": strings.Join(problems, "; ")},
+				"跳过不合法的事件 - 状态页: %s, 第 %d 个, ID: %q, 名称: %q, 原因: %s",
+				page, i+1, incident.ID, incident.Name, strings.Join(problems, "; "))
+			continue
+		}
+		updates := incident.IncidentUpdates[:0]
+		for j, update := range incident.IncidentUpdates {
+			if strings.TrimSpace(update.ID) == "" || update.CreatedAt.IsZero() {
+				logEvent("warn", "fetch", logFields{"page": page, "incident_id": incident.ID, "update_index": j},
+					"跳过事件 %s 中不合法的第 %d 条更新（id 为空或 created_at 无法解析）", incident.ID, j+1)
+				continue
+			}
+			updates = append(updates, update)
+		}
+		incident.IncidentUpdates = updates
+		valid = append(valid, incident)
+	}
+	if skipped := len(incidents) - len(valid); skipped > 0 {
+		logWarnf("状态页 %s 返回的 %d 个事件中有 %d 个不合法，已跳过", page, len(incidents), skipped)
+	}
+	return valid
+}
+
+// 同一 ID 出现多次时的处理方式
+const (
+	duplicatePolicyLatest = "latest" // 保留 updated_at 最新的一条，相同时保留先出现的
+	duplicatePolicyFirst  = "first"  // 保留先出现的一条
+)
+
+// 一次获取的事件中同一 ID 出现多次时（镜像异常、分页错误等）只保留一条并记录警告，
+// 避免写入缓存时后出现的条目静默覆盖前面的条目。保留的事件位于该 ID 首次出现的位置
+func dedupeIncidents(incidents []Incident, policy string) []Incident {
+	index := make(map[string]int, len(incidents))
+	result := incidents[:0]
+	for _, incident := range incidents {
+		i, ok := index[incident.ID]
+		if !ok {
+			index[incident.ID] = len(result)
+			result = append(result, incident)
+			continue
+		}
+		kept := result[i]
+		if policy == duplicatePolicyLatest && incident.UpdatedAt.After(kept.UpdatedAt) {
+			kept = incident
+		}
+		logEvent("warn", "fetch", logFields{"incident_id": incident.ID, "policy": policy},
+			"事件 ID 重复 - ID: %s, 状态页: %s / %s, updated_at: %s / %s，按 %s 保留 updated_at 为 %s 的一条",
+			incident.ID, result[i].Page, incident.Page,
+			result[i].UpdatedAt.Format("2006-01-02 15:04:05"), incident.UpdatedAt.Format("2006-01-02 15:04:05"),
+			policy, kept.UpdatedAt.Format("2006-01-02 15:04:05"))
+		result[i] = kept
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookPayload 通用 Webhook 推送的 JSON 结构
+type webhookPayload struct {
+	Kind       string     `json:"kind"`
+	Title      string     `json:"title"`
+	ChangeType string     `json:"change_type,omitempty"`
+	IncidentID string     `json:"incident_id,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	Impact     string     `json:"impact,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	SentAt     time.Time  `json:"sent_at"`
+	Text       string     `json:"text,omitempty"`
+	Incident   *Incident  `json:"incident,omitempty"`
+	// 开启 THREAD_UPDATES 时，后续更新附带接收方对首次通知返回的消息 ID，便于作为回复发送
+	ReplyTo string `json:"reply_to,omitempty"`
+	// 开启 STATUS_BOARD 时，状态面板更新附带接收方上次返回的消息 ID，接收方应编辑该消息而不是发送新消息
+	EditMessageID string `json:"edit_message_id,omitempty"`
+}
+
+// webhookNotifier 将事件以 JSON 形式推送到自定义地址
+type webhookNotifier struct {
+	url              string
+	token            string
+	signingSecret    string
+	maxResponseBytes int64
+	threads          *threadStore // 开启 THREAD_UPDATES 时记录首次通知的消息 ID，否则为 nil
+	now              func() time.Time
+}
+
+// now 用于生成 sent_at 和签名时间戳，与服务使用同一时钟
+func newWebhookNotifier(url, token, signingSecret string, maxResponseBytes int64, now func() time.Time) *webhookNotifier {
+	return &webhookNotifier{url: url, token: token, signingSecret: signingSecret, maxResponseBytes: maxResponseBytes, now: now}
+}
+
+func (w *webhookNotifier) Name() string {
+	return "webhook"
+}
+
+// 有事件变化时逐个推送，否则推送整条通知文本
+func (w *webhookNotifier) Send(ctx context.Context, n Notification) error {
+	if len(n.Events) == 0 {
+		_, err := w.post(ctx, webhookPayload{
+			Kind:   n.Kind,
+			Title:  n.Title,
+			SentAt: w.now(),
+			Text:   n.Content,
+		})
+		return err
+	}
+
+	for _, event := range n.Events {
+		incident := event.Incident
+		payload := webhookPayload{
+			Kind:       n.Kind,
+			Title:      n.Title,
+			ChangeType: event.ChangeType,
+			IncidentID: incident.ID,
+			Name:       incident.Name,
+			Status:     incident.Status,
+			Impact:     incident.Impact,
+			CreatedAt:  &incident.CreatedAt,
+			UpdatedAt:  &incident.UpdatedAt,
+			SentAt:     w.now(),
+			Incident:   &incident,
+		}
+		if resolvedAt := incident.resolvedTime(); !resolvedAt.IsZero() {
+			payload.ResolvedAt = &resolvedAt
+		}
+		if w.threads != nil && event.ChangeType != changeTypeNew {
+			payload.ReplyTo, _ = w.threads.MessageID(incident.ID, w.Name())
+		}
+		respBody, err := w.post(ctx, payload)
+		if err != nil {
+			return err
+		}
+		if w.threads != nil && event.ChangeType == changeTypeNew {
+			if messageID := parseWebhookMessageID(respBody); messageID != "" {
+				w.threads.RecordMessageID(incident.ID, w.Name(), messageID)
+			}
+		}
+	}
+	return nil
+}
+
+// 推送状态面板，messageID 非空时请求接收方编辑该消息。
+// 接收方返回新的 message_id 时使用新 ID，否则继续使用原消息；首次推送没有返回 message_id 时，
+// 之后每次列表变化都不带 edit_message_id，接收方会把它当作新消息
+func (w *webhookNotifier) SendBoard(ctx context.Context, n Notification, messageID string) (string, error) {
+	respBody, err := w.post(ctx, webhookPayload{
+		Kind:          n.Kind,
+		Title:         n.Title,
+		SentAt:        w.now(),
+		Text:          n.Content,
+		EditMessageID: messageID,
+	})
+	if err != nil {
+		return "", err
+	}
+	if newID := parseWebhookMessageID(respBody); newID != "" {
+		return newID, nil
+	}
+	return messageID, nil
+}
+
+// 计算 Webhook 请求签名。签名字符串为 Unix 秒级时间戳、换行符和原始请求体依次拼接，
+// 即 timestamp + "\n" + body，使用 WEBHOOK_SIGNING_SECRET 计算 HMAC-SHA256 后 Base64 编码
+func signWebhookBody(secret string, body []byte, now time.Time) (timestamp, signature string) {
+	timestamp = strconv.FormatInt(now.Unix(), 10)
+	message := make([]byte, 0, len(timestamp)+1+len(body))
+	message = append(message, timestamp...)
+	message = append(message, '\n')
+	message = append(message, body...)
+	return timestamp, hmacSHA256Base64(secret, message)
+}
+
+// 发送一次推送，成功时返回响应内容
+func (w *webhookNotifier) post(ctx context.Context, payload webhookPayload) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("生成 Webhook JSON 失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建 Webhook 请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+	if w.signingSecret != "" {
+		timestamp, signature := signWebhookBody(w.signingSecret, jsonData, w.now())
+		req.Header.Set("X-Timestamp", timestamp)
+		req.Header.Set("X-Signature", signature)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送 Webhook 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := readLimited(resp.Body, w.maxResponseBytes)
+	logDebugf("Webhook 响应: HTTP状态码=%d, 响应内容=%s", resp.StatusCode, string(respBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("Webhook 返回异常状态码: %d", resp.StatusCode)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Webhook 签名为 HMAC-SHA256(WEBHOOK_SIGNING_SECRET, timestamp + "\n" + body) 的 Base64 编码，
+// 期望值按文档中的算法独立计算，接收方据此校验请求
+func TestSignWebhookBody(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		body          string
+		wantSignature string
+	}{
+		{name: "JSON 请求体", body: `{"kind":"test"}`, wantSignature: "i2quOnZEDnufoeYTq0nUB2GOSfuGiaDRhEDt8Xan3Z8="},
+		{name: "空请求体", body: "", wantSignature: "QzmfbMXt1DRzcGPKKeOvwGL52nBuHDbAjpmRg3ZK8RM="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp, signature := signWebhookBody("whsec", []byte(tt.body), now)
+			if timestamp != "1709296200" {
+				t.Errorf("timestamp = %q, 期望 Unix 秒级时间戳 1709296200", timestamp)
+			}
+			if signature != tt.wantSignature {
+				t.Errorf("signature = %q, 期望 %q", signature, tt.wantSignature)
+			}
+		})
+	}
+}
+
+// 配置了签名密钥时请求带 X-Timestamp 和 X-Signature 头，签名覆盖实际发送的请求体
+func TestWebhookSignatureHeaders(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	notifier := newWebhookNotifier(server.URL, "tok", "whsec", 1<<20, func() time.Time { return now })
+	if err := notifier.Send(context.Background(), Notification{Kind: "test", Title: "测试", Content: "内容"}); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	if got := header.Get("Authorization"); got != "Bearer tok" {
+		t.Errorf("Authorization = %q, 期望 %q", got, "Bearer tok")
+	}
+	if got := header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, 期望 application/json", got)
+	}
+	if got := header.Get("X-Timestamp"); got != "1709296200" {
+		t.Errorf("X-Timestamp = %q, 期望注入时钟的 Unix 秒级时间戳 1709296200", got)
+	}
+	wantBody := `{"kind":"test","title":"测试","sent_at":"2024-03-01T12:30:00Z","text":"内容"}`
+	if string(body) != wantBody {
+		t.Errorf("请求体 = %s, 期望 %s", body, wantBody)
+	}
+	_, want := signWebhookBody("whsec", []byte(wantBody), now)
+	if got := header.Get("X-Signature"); got != want {
+		t.Errorf("X-Signature = %q, 期望 %q", got, want)
+	}
+}
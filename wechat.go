@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// 企业微信 Markdown 消息正文的最大字节数
+const wechatWorkMaxMessageBytes = 4096
+
+// 企业微信群机器人接口地址
+const wechatWorkWebhookURL = "https://qyapi.weixin.qq.com/cgi-bin/webhook/send"
+
+// wechatWorkResponse 企业微信机器人接口的响应
+type wechatWorkResponse struct {
+	Errcode int    `json:"errcode"`
+	Errmsg  string `json:"errmsg"`
+}
+
+// wechatWorkNotifier 企业微信群机器人通知渠道，无需签名，只需要 Webhook 的 key
+type wechatWorkNotifier struct {
+	key              string
+	maxResponseBytes int64
+}
+
+func newWechatWorkNotifier(key string, maxResponseBytes int64) *wechatWorkNotifier {
+	return &wechatWorkNotifier{key: key, maxResponseBytes: maxResponseBytes}
+}
+
+func (w *wechatWorkNotifier) Name() string {
+	return "wechat_work"
+}
+
+func (w *wechatWorkNotifier) Send(ctx context.Context, n Notification) error {
+	parts := splitMessage(toWechatWorkMarkdown(n.Content), wechatWorkMaxMessageBytes)
+	for i, part := range parts {
+		logDebugf("准备发送企业微信通知 - 标题: %s", partTitle(n.Title, i, len(parts)))
+		if err := w.post(ctx, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *wechatWorkNotifier) post(ctx context.Context, content string) error {
+	message := map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": content},
+	}
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("生成企业微信消息 JSON 失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wechatWorkWebhookURL+"?key="+url.QueryEscape(w.key), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("创建企业微信请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送企业微信 HTTP 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := readLimited(resp.Body, w.maxResponseBytes)
+	if err != nil {
+		return fmt.Errorf("读取企业微信响应失败: %v", err)
+	}
+	logDebugf("企业微信响应: HTTP状态码=%d, 响应内容=%s", resp.StatusCode, string(respBody))
+
+	var result wechatWorkResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("解析企业微信响应失败: %v", err)
+	}
+	if result.Errcode != 0 {
+		return fmt.Errorf("企业微信返回错误: errcode=%d, errmsg=%s", result.Errcode, result.Errmsg)
+	}
+	return nil
+}
+
+var fontColorPattern = regexp.MustCompile(`<font color="([^"]*)">`)
+
+// 钉钉颜色到企业微信仅支持的三种字体颜色的映射
+var wechatWorkColors = map[string]string{
+	impactColors["critical"]: "warning",
+	impactColors["major"]:    "warning",
+	impactColors["minor"]:    "comment",
+	impactColors["none"]:     "comment",
+}
+
+// 将钉钉风格的 Markdown 转换为企业微信 Markdown：字体颜色只支持 info、comment、warning，
+// 不支持分隔线
+func toWechatWorkMarkdown(content string) string {
+	content = fontColorPattern.ReplaceAllStringFunc(content, func(tag string) string {
+		color := wechatWorkColors[fontColorPattern.FindStringSubmatch(tag)[1]]
+		if color == "" {
+			color = "comment"
+		}
+		return `<font color="` + color + `">`
+	})
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "---" {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}